@@ -2,8 +2,18 @@ package db_benchmark
 
 import (
 	"encoding/binary"
+
+	"github.com/boreq/errors"
 )
 
+// ErrNotFound is returned by Reader.Get if a value with the given sequence
+// doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned by DatabaseSystem.Update if the transaction
+// conflicted with a concurrent transaction. The update can be retried.
+var ErrConflict = errors.New("conflict")
+
 type DatabaseSystem interface {
 	Update(func(updater Updater) error) error
 	Read(func(reader Reader) error) error
@@ -12,15 +22,49 @@ type DatabaseSystem interface {
 	PreferredTransactionSize() int
 }
 
+// Compactor is implemented by database systems which can reclaim the space
+// taken by deleted values.
+type Compactor interface {
+	// Compact blocks until the compaction is completed.
+	Compact() error
+}
+
 type Updater interface {
-	Append(value []byte) error
+	// Append appends the value to the log and returns the sequence which
+	// was assigned to it. Sequences start at 0.
+	Append(value []byte) (Sequence, error)
+
+	// Delete removes the value with the given sequence. Sequences of
+	// deleted values are never reused. Deleting a value which doesn't
+	// exist isn't an error.
+	Delete(seq Sequence) error
+
+	// DeleteRange removes all values with a sequence in the range
+	// [start, end).
+	DeleteRange(start, end Sequence) error
 }
 
 type Reader interface {
 	Get(seq Sequence) ([]byte, error)
 	Iterate(start Sequence, limit int, fn func(item Item) error) error
+
+	// IterateRange calls fn for every value with a sequence in the range
+	// [start, end) visiting them in the given direction.
+	IterateRange(start, end Sequence, direction Direction, fn func(item Item) error) error
+
+	// LastSequence returns the last sequence which was assigned by
+	// Updater.Append, even if that value was deleted. False is returned if
+	// nothing was appended yet.
+	LastSequence() (Sequence, bool, error)
 }
 
+type Direction int
+
+const (
+	Forward Direction = iota
+	Backward
+)
+
 type Item struct {
 	Sequence Sequence
 	Value    []byte
@@ -28,12 +72,14 @@ type Item struct {
 
 type Sequence uint64
 
+// marshalSequence uses big-endian so that the byte-wise order of the keys
+// matches the numeric order of the sequences.
 func marshalSequence(v Sequence) []byte {
 	b := make([]byte, 8)
-	binary.LittleEndian.PutUint64(b, uint64(v))
+	binary.BigEndian.PutUint64(b, uint64(v))
 	return b
 }
 
 func unmarshalSequence(b []byte) Sequence {
-	return Sequence(binary.LittleEndian.Uint64(b))
+	return Sequence(binary.BigEndian.Uint64(b))
 }
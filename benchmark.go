@@ -0,0 +1,551 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/boreq/errors"
+)
+
+const (
+	AppendWorkload      = "append"
+	ReadRandomWorkload  = "read_random"
+	ReadIterateWorkload = "read_iterate"
+)
+
+// DefaultAppendValues is the number of values appended by every op of the
+// append workload.
+const DefaultAppendValues = 5000
+
+const (
+	readRandomSequencesMaxSequence             = 100000
+	readRandomSequencesNumberOfSequencesToRead = 5000
+)
+
+type TestedDatabaseSystem struct {
+	Name                      string
+	DatabaseSystemConstructor DatabaseSystemConstructor
+
+	// Config is set if the database system was created using
+	// NewTestedDatabaseSystem.
+	Config DatabaseSystemConfig
+}
+
+type DatabaseSystemConstructor func(dir string) (DatabaseSystem, error)
+
+type StorageSystem struct {
+	Name string
+	Path string
+}
+
+type BenchmarkEnvironment struct {
+	TestedDatabaseSystem TestedDatabaseSystem
+	DataConstructor      DataConstructor
+
+	// Dir is the directory in which the database system is stored.
+	Dir string
+
+	// Rand is seeded with the seed of the run and should be used for all
+	// random choices and passed to the data constructor so that runs
+	// using the same seed are reproducible. It isn't safe for concurrent
+	// use.
+	Rand *rand.Rand
+}
+
+type Benchmark struct {
+	Name      string
+	SetupFunc BenchmarkFunc
+	Func      BenchmarkFunc
+
+	// ColdCache causes the database system to be closed, evicted from the
+	// page cache and reopened before every execution of Func so that the
+	// measured operations can't benefit from the data cached by the
+	// operating system. Reopening isn't measured.
+	ColdCache bool
+
+	// Recreate causes the database system to be closed and created again
+	// in an empty directory before every execution of Func but the first
+	// one, so that every execution starts from the same state. Recreating
+	// isn't measured.
+	Recreate bool
+
+	// ShutdownFunc is optional. If it is set it is called after SetupFunc
+	// and has to shut down the database system. Before every execution of
+	// Func the database system is then created again from a copy of the
+	// directory left behind by ShutdownFunc. Creating the database system
+	// is measured together with Func. If ColdCache is set the copy is
+	// evicted from the page cache.
+	ShutdownFunc BenchmarkFunc
+}
+
+type BenchmarkFunc func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error
+
+func Benchmarks() []Benchmark {
+	var benchmarks []Benchmark
+
+	benchmarks = append(benchmarks, NewAppendBenchmark(DefaultAppendValues))
+
+	benchmarks = append(benchmarks, []Benchmark{
+		NewReadRandomBenchmark(NewUniformKeyChooser()),
+		{
+			Name:      "read_sequential",
+			SetupFunc: appendValuesSetupFunc(readRandomSequencesMaxSequence),
+			Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+				if err := databaseSystem.Read(func(reader Reader) error {
+					for i := 0; i < readRandomSequencesNumberOfSequencesToRead; i++ {
+						value, err := reader.Get(Sequence(i))
+						if err != nil {
+							return errors.Wrap(err, "error calling get")
+						}
+						if len(value) == 0 {
+							b.Fatal("got an empty value")
+						}
+					}
+					return nil
+				}); err != nil {
+					return errors.Wrap(err, "error calling read")
+				}
+				return nil
+			},
+		},
+		NewReadIterateBenchmark(NewUniformKeyChooser()),
+		{
+			Name:      "read_iterate_reverse",
+			SetupFunc: appendValuesSetupFunc(readRandomSequencesMaxSequence),
+			Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+				if err := databaseSystem.Read(func(reader Reader) error {
+					lastSequence, err := getLastSequence(reader)
+					if err != nil {
+						return errors.Wrap(err, "error getting last sequence")
+					}
+
+					end := Sequence(env.Rand.Int63n(int64(lastSequence)+1)) + 1
+
+					var start Sequence
+					if end > readRandomSequencesNumberOfSequencesToRead {
+						start = end - readRandomSequencesNumberOfSequencesToRead
+					}
+
+					if err := reader.IterateRange(
+						start,
+						end,
+						Backward,
+						func(item Item) error {
+							return nil
+						}); err != nil {
+						return errors.Wrap(err, "error iterating")
+					}
+					return nil
+				}); err != nil {
+					return errors.Wrap(err, "error calling read")
+				}
+				return nil
+			},
+		},
+	}...)
+
+	benchmarks = append(benchmarks, keyIndexBenchmarks()...)
+
+	return benchmarks
+}
+
+// NewAppendBenchmark returns a benchmark which appends the given number of
+// values in every op. The values are split into updates containing the
+// preferred transaction size of the database system so the number of values
+// has to be at least as large as the transaction size for the transaction
+// size to be measured.
+func NewAppendBenchmark(numberOfValues int) Benchmark {
+	return Benchmark{
+		Name: AppendWorkload,
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			for _, n := range batch(numberOfValues, databaseSystem.PreferredTransactionSize()) {
+				if err := databaseSystem.Update(func(updater Updater) error {
+					for i := 0; i < n; i++ {
+						if _, err := updater.Append(env.DataConstructor.Fn(env.Rand)); err != nil {
+							return errors.Wrap(err, "error calling set")
+						}
+					}
+					return nil
+				}); err != nil {
+					return errors.Wrap(err, "error calling update")
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// NewReadRandomBenchmark returns a benchmark which gets values with
+// sequences selected by the key chooser. The name of the key chooser is
+// appended to the name of the benchmark unless the key chooser is uniform.
+func NewReadRandomBenchmark(keyChooser KeyChooser) Benchmark {
+	return Benchmark{
+		Name:      keyChooserBenchmarkName(ReadRandomWorkload, keyChooser),
+		SetupFunc: appendValuesSetupFunc(readRandomSequencesMaxSequence),
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			if err := databaseSystem.Read(func(reader Reader) error {
+				lastSequence, err := getLastSequence(reader)
+				if err != nil {
+					return errors.Wrap(err, "error getting last sequence")
+				}
+
+				for i := 0; i < readRandomSequencesNumberOfSequencesToRead; i++ {
+					value, err := reader.Get(keyChooser.Choose(env.Rand, lastSequence))
+					if err != nil {
+						return errors.Wrap(err, "error calling get")
+					}
+					if len(value) == 0 {
+						b.Fatal("got an empty value")
+					}
+				}
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "error calling read")
+			}
+			return nil
+		},
+	}
+}
+
+// NewReadIterateBenchmark returns a benchmark which iterates over values
+// starting at a sequence selected by the key chooser. The name of the key
+// chooser is appended to the name of the benchmark unless the key chooser is
+// uniform.
+func NewReadIterateBenchmark(keyChooser KeyChooser) Benchmark {
+	return Benchmark{
+		Name:      keyChooserBenchmarkName(ReadIterateWorkload, keyChooser),
+		SetupFunc: appendValuesSetupFunc(readRandomSequencesMaxSequence),
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			if err := databaseSystem.Read(func(reader Reader) error {
+				lastSequence, err := getLastSequence(reader)
+				if err != nil {
+					return errors.Wrap(err, "error getting last sequence")
+				}
+
+				if err := reader.Iterate(
+					keyChooser.Choose(env.Rand, lastSequence),
+					readRandomSequencesNumberOfSequencesToRead,
+					func(item Item) error {
+						return nil
+					}); err != nil {
+					return errors.Wrap(err, "error iterating")
+				}
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "error calling read")
+			}
+			return nil
+		},
+	}
+}
+
+func keyChooserBenchmarkName(name string, keyChooser KeyChooser) string {
+	if _, ok := keyChooser.(UniformKeyChooser); ok {
+		return name
+	}
+	return fmt.Sprintf("%s_%s", name, keyChooser.Name())
+}
+
+// appendValuesSetupFunc returns a setup function which appends the given
+// number of values to the database system.
+func appendValuesSetupFunc(numberOfValues int) BenchmarkFunc {
+	return func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+		for _, n := range batch(numberOfValues, databaseSystem.PreferredTransactionSize()) {
+			if err := databaseSystem.Update(func(updater Updater) error {
+				for i := 0; i < n; i++ {
+					if _, err := updater.Append(env.DataConstructor.Fn(env.Rand)); err != nil {
+						return errors.Wrap(err, "error calling append")
+					}
+				}
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "error calling update")
+			}
+		}
+		return nil
+	}
+}
+
+// RunBenchmark executes a single benchmark against a freshly created
+// database system located in the given storage system. Every execution is
+// seeded with the same seed so that it appends the same values and performs
+// the same operations.
+func RunBenchmark(b *testing.B, testedDatabaseSystem TestedDatabaseSystem, storageSystem StorageSystem, dataConstructor DataConstructor, benchmark Benchmark, seed int64) error {
+	dir := fixtures.Directory(b, storageSystem.Path)
+
+	env := BenchmarkEnvironment{
+		TestedDatabaseSystem: testedDatabaseSystem,
+		DataConstructor:      dataConstructor,
+		Dir:                  dir,
+		Rand:                 rand.New(rand.NewSource(seed)),
+	}
+
+	if err := dataConstructor.reset(); err != nil {
+		return errors.Wrap(err, "error resetting the data constructor")
+	}
+
+	system, err := testedDatabaseSystem.DatabaseSystemConstructor(dir)
+	if err != nil {
+		return errors.Wrap(err, "error creating the database system")
+	}
+
+	if benchmark.SetupFunc != nil {
+		if err := benchmark.SetupFunc(b, system, env); err != nil {
+			return errors.Wrap(err, "setup function returned an error")
+		}
+	}
+
+	var snapshotDir string
+	if benchmark.ShutdownFunc != nil {
+		if err := benchmark.ShutdownFunc(b, system, env); err != nil {
+			return errors.Wrap(err, "shutdown function returned an error")
+		}
+
+		snapshotDir = dir
+		env.Dir = fixtures.Directory(b, storageSystem.Path)
+		system = nil
+	}
+
+	instrumentedSystem := NewInstrumentedDatabaseSystem(system)
+
+	b.ResetTimer()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		if benchmark.ShutdownFunc != nil {
+			b.StopTimer()
+
+			if err := prepareReopen(system, snapshotDir, env.Dir, benchmark.ColdCache); err != nil {
+				return errors.Wrap(err, "error preparing to reopen the database system")
+			}
+
+			b.StartTimer()
+
+			system, err = testedDatabaseSystem.DatabaseSystemConstructor(env.Dir)
+			if err != nil {
+				return errors.Wrap(err, "error reopening the database system")
+			}
+			instrumentedSystem.DatabaseSystem = system
+		} else if benchmark.Recreate && i > 0 {
+			b.StopTimer()
+
+			system, err = recreate(testedDatabaseSystem, system, dir)
+			if err != nil {
+				return errors.Wrap(err, "error recreating the database system")
+			}
+			instrumentedSystem.DatabaseSystem = system
+
+			b.StartTimer()
+		} else if benchmark.ColdCache {
+			b.StopTimer()
+
+			system, err = reopenWithColdCache(testedDatabaseSystem, system, dir)
+			if err != nil {
+				return errors.Wrap(err, "error reopening the database system with a cold cache")
+			}
+			instrumentedSystem.DatabaseSystem = system
+
+			b.StartTimer()
+		}
+
+		if err := benchmark.Func(b, instrumentedSystem, env); err != nil {
+			return errors.Wrap(err, "benchmark function returned an error")
+		}
+	}
+
+	if err := dataConstructor.err(); err != nil {
+		return errors.Wrap(err, "data constructor returned an error")
+	}
+
+	if err := system.Sync(); err != nil {
+		return errors.Wrap(err, "error calling sync")
+	}
+
+	b.StopTimer()
+
+	reportLatencies(b, instrumentedSystem.Latencies())
+
+	if err := system.Close(); err != nil {
+		return errors.Wrap(err, "error calling close")
+	}
+
+	return nil
+}
+
+// reopenWithColdCache closes the database system, evicts its files from the
+// page cache and creates it again.
+func reopenWithColdCache(testedDatabaseSystem TestedDatabaseSystem, system DatabaseSystem, dir string) (DatabaseSystem, error) {
+	if err := system.Close(); err != nil {
+		return nil, errors.Wrap(err, "error calling close")
+	}
+
+	if err := evictFromPageCache(dir); err != nil {
+		return nil, errors.Wrap(err, "error evicting the files from the page cache")
+	}
+
+	system, err := testedDatabaseSystem.DatabaseSystemConstructor(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the database system")
+	}
+
+	return system, nil
+}
+
+// recreate closes the database system, removes its directory and creates it
+// again in an empty directory.
+func recreate(testedDatabaseSystem TestedDatabaseSystem, system DatabaseSystem, dir string) (DatabaseSystem, error) {
+	if err := system.Close(); err != nil {
+		return nil, errors.Wrap(err, "error calling close")
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, errors.Wrap(err, "error removing the directory")
+	}
+
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating the directory")
+	}
+
+	system, err := testedDatabaseSystem.DatabaseSystemConstructor(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the database system")
+	}
+
+	return system, nil
+}
+
+// RunSizeBenchmark inserts b.N values into a freshly created database system
+// and reports the resulting size of its directory per inserted value.
+func RunSizeBenchmark(b *testing.B, testedDatabaseSystem TestedDatabaseSystem, dataConstructor DataConstructor, seed int64) error {
+	const maxValuesPerTransaction = 1000
+
+	dir := fixtures.Directory(b, "")
+	rnd := rand.New(rand.NewSource(seed))
+
+	if err := dataConstructor.reset(); err != nil {
+		return errors.Wrap(err, "error resetting the data constructor")
+	}
+
+	system, err := testedDatabaseSystem.DatabaseSystemConstructor(dir)
+	if err != nil {
+		return errors.Wrap(err, "error creating the database system")
+	}
+
+	b.ResetTimer()
+	b.StartTimer()
+
+	var insertedValues int
+
+	for {
+		valuesToInsert := b.N - insertedValues
+		if valuesToInsert > maxValuesPerTransaction {
+			valuesToInsert = maxValuesPerTransaction
+		}
+
+		if err := system.Update(func(updater Updater) error {
+			for n := 0; n < valuesToInsert; n++ {
+				if _, err := updater.Append(dataConstructor.Fn(rnd)); err != nil {
+					return errors.Wrap(err, "error calling append")
+				}
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error calling update")
+		}
+
+		insertedValues += valuesToInsert
+		if insertedValues >= b.N {
+			break
+		}
+	}
+
+	if err := dataConstructor.err(); err != nil {
+		return errors.Wrap(err, "data constructor returned an error")
+	}
+
+	if err := system.Sync(); err != nil {
+		return errors.Wrap(err, "error calling sync")
+	}
+
+	b.StopTimer()
+
+	if err := system.Close(); err != nil {
+		return errors.Wrap(err, "error calling close")
+	}
+
+	size, err := dirSize(dir)
+	if err != nil {
+		return errors.Wrap(err, "error checking directory size")
+	}
+
+	bytesPerInsert := float64(size) / float64(b.N)
+	b.Logf("Run bench=%s with b.n=%d directory size: %d (%.0f per insert)", b.Name(), b.N, size, bytesPerInsert)
+
+	b.ReportMetric(bytesPerInsert, "bytes/op")
+	b.ReportMetric(0, "ns/op")
+
+	return nil
+}
+
+func getLastSequence(reader Reader) (Sequence, error) {
+	lastSequence, ok, err := reader.LastSequence()
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling last sequence")
+	}
+
+	if !ok {
+		return 0, errors.New("database is empty")
+	}
+
+	return lastSequence, nil
+}
+
+// dirDiskUsage returns the number of bytes allocated on disk for all files
+// in the directory.
+func dirDiskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += diskUsage(info)
+		}
+		return err
+	})
+	return size, err
+}
+
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return err
+	})
+	return size, err
+}
+
+func batch(total, batchSize int) []int {
+	var batches []int
+
+	for {
+		if total > batchSize {
+			batches = append(batches, batchSize)
+			total -= batchSize
+		} else {
+			batches = append(batches, total)
+			break
+		}
+	}
+
+	return batches
+}
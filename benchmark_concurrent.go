@@ -0,0 +1,176 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/boreq/errors"
+)
+
+const (
+	ConcurrentWorkload = "concurrent"
+
+	DefaultConcurrentReaders        = 4
+	DefaultConcurrentWriters        = 1
+	DefaultConcurrentReadPercentage = 90
+)
+
+// NewConcurrentBenchmark returns a benchmark in which readers and writers
+// access the database system at the same time from separate goroutines. Each
+// read gets a random value in a separate read transaction and each write
+// appends a single value in a separate update transaction which is retried
+// if it conflicts with a different one. Read percentage controls which part
+// of all operations is performed by the readers. The throughput of each role
+// is reported separately.
+func NewConcurrentBenchmark(readers, writers, readPercentage int) Benchmark {
+	const numberOfValues = 100000
+	const numberOfOperations = 10000
+
+	numberOfReads := numberOfOperations * readPercentage / 100
+	numberOfWrites := numberOfOperations - numberOfReads
+
+	return Benchmark{
+		Name:      fmt.Sprintf("%s_%d_readers_%d_writers_%d_percent_reads", ConcurrentWorkload, readers, writers, readPercentage),
+		SetupFunc: appendValuesSetupFunc(numberOfValues),
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			var lastSequence Sequence
+			if err := databaseSystem.Read(func(reader Reader) error {
+				v, err := getLastSequence(reader)
+				if err != nil {
+					return errors.Wrap(err, "error getting last sequence")
+				}
+				lastSequence = v
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "error calling read")
+			}
+
+			start := time.Now()
+
+			readersResult := runConcurrently(env.Rand, split(numberOfReads, readers), func(rnd *rand.Rand) error {
+				return databaseSystem.Read(func(reader Reader) error {
+					value, err := reader.Get(Sequence(rnd.Int63n(int64(lastSequence) + 1)))
+					if err != nil {
+						return errors.Wrap(err, "error calling get")
+					}
+					if len(value) == 0 {
+						return errors.New("got an empty value")
+					}
+					return nil
+				})
+			})
+
+			var conflicts int64
+
+			writersResult := runConcurrently(env.Rand, split(numberOfWrites, writers), func(rnd *rand.Rand) error {
+				for {
+					err := databaseSystem.Update(func(updater Updater) error {
+						if _, err := updater.Append(env.DataConstructor.Fn(rnd)); err != nil {
+							return errors.Wrap(err, "error calling append")
+						}
+						return nil
+					})
+					if errors.Is(err, ErrConflict) {
+						atomic.AddInt64(&conflicts, 1)
+						continue
+					}
+					return err
+				}
+			})
+
+			readersEnd, err := readersResult.Wait()
+			if err != nil {
+				return errors.Wrap(err, "reader returned an error")
+			}
+
+			writersEnd, err := writersResult.Wait()
+			if err != nil {
+				return errors.Wrap(err, "writer returned an error")
+			}
+
+			if numberOfReads > 0 {
+				b.ReportMetric(float64(numberOfReads)/readersEnd.Sub(start).Seconds(), "reads/s")
+			}
+
+			if numberOfWrites > 0 {
+				b.ReportMetric(float64(numberOfWrites)/writersEnd.Sub(start).Seconds(), "writes/s")
+				b.ReportMetric(float64(conflicts), "write_conflicts")
+			}
+
+			return nil
+		},
+	}
+}
+
+type concurrentResult struct {
+	wg   sync.WaitGroup
+	errs []error
+	ends []time.Time
+}
+
+// runConcurrently starts a goroutine for each element of operations which
+// calls fn the specified number of times. Each goroutine receives its own
+// source of randomness seeded using rnd.
+func runConcurrently(rnd *rand.Rand, operations []int, fn func(rnd *rand.Rand) error) *concurrentResult {
+	result := &concurrentResult{
+		errs: make([]error, len(operations)),
+		ends: make([]time.Time, len(operations)),
+	}
+
+	for i := range operations {
+		i := i
+		goroutineRnd := rand.New(rand.NewSource(rnd.Int63()))
+
+		result.wg.Add(1)
+		go func() {
+			defer result.wg.Done()
+
+			for j := 0; j < operations[i]; j++ {
+				if err := fn(goroutineRnd); err != nil {
+					result.errs[i] = err
+					break
+				}
+			}
+
+			result.ends[i] = time.Now()
+		}()
+	}
+
+	return result
+}
+
+// Wait waits for all goroutines to finish and returns the time at which the
+// last one of them finished.
+func (r *concurrentResult) Wait() (time.Time, error) {
+	r.wg.Wait()
+
+	var end time.Time
+	for i := range r.ends {
+		if r.errs[i] != nil {
+			return time.Time{}, r.errs[i]
+		}
+
+		if r.ends[i].After(end) {
+			end = r.ends[i]
+		}
+	}
+
+	return end, nil
+}
+
+// split divides total into n parts which differ by at most one.
+func split(total, n int) []int {
+	var parts []int
+	for i := 0; i < n; i++ {
+		part := total / n
+		if i < total%n {
+			part++
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
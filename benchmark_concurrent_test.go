@@ -0,0 +1,58 @@
+package db_benchmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentBenchmarkReportsThroughput(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		ReadPercentage  int
+		ExpectedMetrics []string
+		MissingMetrics  []string
+	}{
+		{
+			Name:            "reads_and_writes",
+			ReadPercentage:  50,
+			ExpectedMetrics: []string{"reads/s", "writes/s", "write_conflicts"},
+		},
+		{
+			Name:            "only_writes",
+			ReadPercentage:  0,
+			ExpectedMetrics: []string{"writes/s", "write_conflicts"},
+			MissingMetrics:  []string{"reads/s"},
+		},
+	}
+
+	system, err := NewTestedDatabaseSystem(DatabaseSystemConfig{
+		Type:            BoltDatabaseSystemType,
+		Codec:           CodecNone,
+		TransactionSize: DefaultTransactionSize,
+		Durability:      DurabilityNone,
+	})
+	require.NoError(t, err)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			benchmark := NewConcurrentBenchmark(2, 2, testCase.ReadPercentage)
+
+			var benchmarkErr error
+			result := testing.Benchmark(func(b *testing.B) {
+				benchmarkErr = RunBenchmark(b, system, StorageSystem{}, DataConstructors()[0], benchmark, 1)
+			})
+			require.NoError(t, benchmarkErr)
+
+			for _, metric := range testCase.ExpectedMetrics {
+				require.Contains(t, result.Extra, metric)
+			}
+
+			for _, metric := range testCase.MissingMetrics {
+				require.NotContains(t, result.Extra, metric)
+			}
+
+			require.Positive(t, result.Extra["writes/s"])
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/boreq/errors"
+)
+
+const (
+	DeleteWorkload  = "delete"
+	CompactWorkload = "compact"
+
+	DefaultDeletePercentage = 50
+)
+
+// NewDeleteBenchmark returns a benchmark which appends values and then
+// deletes the given percentage of them selected at random. Only the deletion
+// is timed. The disk usage of the directory before and after the deletion is
+// reported to show if the database system reclaims the space. Every
+// execution starts with an empty database system so that the sizes always
+// describe the same number of values.
+func NewDeleteBenchmark(percentage int) Benchmark {
+	const numberOfValues = 20000
+
+	return Benchmark{
+		Name:     fmt.Sprintf("%s_%d_percent", DeleteWorkload, percentage),
+		Recreate: true,
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			b.StopTimer()
+
+			// the latencies of appending the values aren't recorded
+			sequences, err := appendValuesSelectingForDeletion(unwrap(databaseSystem), env, numberOfValues, percentage)
+			if err != nil {
+				return errors.Wrap(err, "error appending values")
+			}
+
+			sizeBefore, err := syncAndGetDiskUsage(databaseSystem, env.Dir)
+			if err != nil {
+				return errors.Wrap(err, "error getting disk usage before deleting")
+			}
+
+			b.StartTimer()
+
+			if err := deleteValues(databaseSystem, sequences); err != nil {
+				return errors.Wrap(err, "error deleting values")
+			}
+
+			b.StopTimer()
+
+			sizeAfter, err := syncAndGetDiskUsage(databaseSystem, env.Dir)
+			if err != nil {
+				return errors.Wrap(err, "error getting disk usage after deleting")
+			}
+
+			b.ReportMetric(float64(sizeBefore), "bytes_before_delete")
+			b.ReportMetric(float64(sizeAfter), "bytes_after_delete")
+
+			b.StartTimer()
+
+			return nil
+		},
+	}
+}
+
+// NewCompactBenchmark returns a benchmark which appends values, deletes the
+// given percentage of them and then compacts the database system. Only the
+// compaction is timed. The number of bytes by which the disk usage of the
+// directory decreased is reported. Every execution starts with an empty
+// database system so that the sizes always describe the same number of
+// values. The benchmark is skipped if the database system doesn't implement
+// Compactor.
+func NewCompactBenchmark(percentage int) Benchmark {
+	const numberOfValues = 20000
+
+	return Benchmark{
+		Name:     fmt.Sprintf("%s_%d_percent", CompactWorkload, percentage),
+		Recreate: true,
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			compactor, ok := unwrap(databaseSystem).(Compactor)
+			if !ok {
+				b.Skip("database system doesn't support compaction")
+			}
+
+			b.StopTimer()
+
+			// the latencies of appending and deleting the values aren't
+			// recorded
+			sequences, err := appendValuesSelectingForDeletion(unwrap(databaseSystem), env, numberOfValues, percentage)
+			if err != nil {
+				return errors.Wrap(err, "error appending values")
+			}
+
+			if err := deleteValues(unwrap(databaseSystem), sequences); err != nil {
+				return errors.Wrap(err, "error deleting values")
+			}
+
+			sizeBefore, err := syncAndGetDiskUsage(databaseSystem, env.Dir)
+			if err != nil {
+				return errors.Wrap(err, "error getting disk usage before compacting")
+			}
+
+			b.StartTimer()
+
+			if err := compactor.Compact(); err != nil {
+				return errors.Wrap(err, "error calling compact")
+			}
+
+			b.StopTimer()
+
+			sizeAfter, err := syncAndGetDiskUsage(databaseSystem, env.Dir)
+			if err != nil {
+				return errors.Wrap(err, "error getting disk usage after compacting")
+			}
+
+			b.ReportMetric(float64(sizeBefore), "bytes_before_compact")
+			b.ReportMetric(float64(sizeAfter), "bytes_after_compact")
+			b.ReportMetric(float64(sizeBefore-sizeAfter), "bytes_reclaimed")
+
+			b.StartTimer()
+
+			return nil
+		},
+	}
+}
+
+// appendValuesSelectingForDeletion appends the given number of values and
+// returns the sequences of the given percentage of them selected at random.
+func appendValuesSelectingForDeletion(databaseSystem DatabaseSystem, env BenchmarkEnvironment, numberOfValues int, percentage int) ([]Sequence, error) {
+	var sequences []Sequence
+	for _, n := range batch(numberOfValues, databaseSystem.PreferredTransactionSize()) {
+		if err := databaseSystem.Update(func(updater Updater) error {
+			for i := 0; i < n; i++ {
+				seq, err := updater.Append(env.DataConstructor.Fn(env.Rand))
+				if err != nil {
+					return errors.Wrap(err, "error calling append")
+				}
+
+				if env.Rand.Intn(100) < percentage {
+					sequences = append(sequences, seq)
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, errors.Wrap(err, "error calling update")
+		}
+	}
+	return sequences, nil
+}
+
+func deleteValues(databaseSystem DatabaseSystem, sequences []Sequence) error {
+	for _, n := range batch(len(sequences), databaseSystem.PreferredTransactionSize()) {
+		if err := databaseSystem.Update(func(updater Updater) error {
+			for _, seq := range sequences[:n] {
+				if err := updater.Delete(seq); err != nil {
+					return errors.Wrap(err, "error calling delete")
+				}
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error calling update")
+		}
+
+		sequences = sequences[n:]
+	}
+	return nil
+}
+
+// syncAndGetDiskUsage returns the number of bytes allocated for the files in
+// the directory. dirSize isn't used as badger preallocates sparse files,
+// their apparent size doesn't change when space is reclaimed.
+func syncAndGetDiskUsage(databaseSystem DatabaseSystem, dir string) (int64, error) {
+	if err := databaseSystem.Sync(); err != nil {
+		return 0, errors.Wrap(err, "error calling sync")
+	}
+
+	size, err := dirDiskUsage(dir)
+	if err != nil {
+		return 0, errors.Wrap(err, "error checking disk usage")
+	}
+
+	return size, nil
+}
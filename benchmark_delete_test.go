@@ -0,0 +1,58 @@
+package db_benchmark
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactReclaimsSpace(t *testing.T) {
+	const numberOfValues = 20000
+
+	systems, err := Matrix{
+		Systems: []MatrixSystem{
+			{Type: BoltDatabaseSystemType, Durabilities: []string{DurabilityNone}},
+			{Type: BadgerDatabaseSystemType, Durabilities: []string{DurabilityNone}},
+			{Type: MargaretDatabaseSystemType, Durabilities: []string{DurabilityNone}},
+		},
+	}.DatabaseSystems()
+	require.NoError(t, err)
+
+	for _, system := range systems {
+		t.Run(system.Name, func(t *testing.T) {
+			dir := fixtures.Directory(t, "")
+
+			databaseSystem, err := system.DatabaseSystemConstructor(dir)
+			require.NoError(t, err)
+			defer databaseSystem.Close()
+
+			compactor, ok := databaseSystem.(Compactor)
+			if !ok {
+				t.Skip("database system doesn't support compaction")
+			}
+
+			env := BenchmarkEnvironment{
+				TestedDatabaseSystem: system,
+				DataConstructor:      DataConstructors()[0],
+				Dir:                  dir,
+				Rand:                 rand.New(rand.NewSource(1)),
+			}
+
+			sequences, err := appendValuesSelectingForDeletion(databaseSystem, env, numberOfValues, DefaultDeletePercentage)
+			require.NoError(t, err)
+			require.NoError(t, deleteValues(databaseSystem, sequences))
+
+			sizeBefore, err := syncAndGetDiskUsage(databaseSystem, dir)
+			require.NoError(t, err)
+
+			require.NoError(t, compactor.Compact())
+
+			sizeAfter, err := syncAndGetDiskUsage(databaseSystem, dir)
+			require.NoError(t, err)
+
+			require.Less(t, sizeAfter, sizeBefore, "compaction should reclaim space")
+		})
+	}
+}
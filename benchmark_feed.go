@@ -0,0 +1,140 @@
+package db_benchmark
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/boreq/errors"
+)
+
+const (
+	FeedAppendWorkload  = "feed_append"
+	FeedIterateWorkload = "feed_iterate"
+
+	DefaultNumberOfAuthors = 100
+)
+
+// NewFeedAppendBenchmark returns a benchmark which spreads appends randomly
+// across the feeds of the given number of authors.
+func NewFeedAppendBenchmark(numberOfAuthors int) Benchmark {
+	const numberOfAppendsToPerform = 5000
+
+	authors := newAuthors(numberOfAuthors)
+
+	return Benchmark{
+		Name: fmt.Sprintf("%s_%d_authors", FeedAppendWorkload, numberOfAuthors),
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			feedDatabaseSystem, err := asFeedDatabaseSystem(databaseSystem)
+			if err != nil {
+				return errors.Wrap(err, "error getting the feed database system")
+			}
+
+			for _, n := range batch(numberOfAppendsToPerform, databaseSystem.PreferredTransactionSize()) {
+				if err := feedDatabaseSystem.UpdateFeeds(func(updater FeedUpdater) error {
+					for i := 0; i < n; i++ {
+						author := authors[env.Rand.Intn(len(authors))]
+						if _, err := updater.AppendToFeed(author, env.DataConstructor.Fn(env.Rand)); err != nil {
+							return errors.Wrap(err, "error calling append to feed")
+						}
+					}
+					return nil
+				}); err != nil {
+					return errors.Wrap(err, "error calling update feeds")
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// NewFeedIterateBenchmark returns a benchmark which reads fragments of
+// randomly selected feeds starting at random sequences.
+func NewFeedIterateBenchmark(numberOfAuthors int) Benchmark {
+	const numberOfValues = 100000
+	const numberOfIterations = 50
+	const numberOfValuesToReadPerIteration = 100
+
+	authors := newAuthors(numberOfAuthors)
+
+	return Benchmark{
+		Name: fmt.Sprintf("%s_%d_authors", FeedIterateWorkload, numberOfAuthors),
+		SetupFunc: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			feedDatabaseSystem, err := asFeedDatabaseSystem(databaseSystem)
+			if err != nil {
+				return errors.Wrap(err, "error getting the feed database system")
+			}
+
+			var appended int
+			for _, n := range batch(numberOfValues, databaseSystem.PreferredTransactionSize()) {
+				if err := feedDatabaseSystem.UpdateFeeds(func(updater FeedUpdater) error {
+					for i := 0; i < n; i++ {
+						author := authors[appended%len(authors)]
+						if _, err := updater.AppendToFeed(author, env.DataConstructor.Fn(env.Rand)); err != nil {
+							return errors.Wrap(err, "error calling append to feed")
+						}
+						appended++
+					}
+					return nil
+				}); err != nil {
+					return errors.Wrap(err, "error calling update feeds")
+				}
+			}
+			return nil
+		},
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			feedDatabaseSystem, err := asFeedDatabaseSystem(databaseSystem)
+			if err != nil {
+				return errors.Wrap(err, "error getting the feed database system")
+			}
+
+			if err := feedDatabaseSystem.ReadFeeds(func(reader FeedReader) error {
+				for i := 0; i < numberOfIterations; i++ {
+					author := authors[env.Rand.Intn(len(authors))]
+
+					lastSequence, ok, err := reader.LastFeedSequence(author)
+					if err != nil {
+						return errors.Wrap(err, "error calling last feed sequence")
+					}
+
+					if !ok {
+						return errors.New("feed is empty")
+					}
+
+					from := Sequence(env.Rand.Int63n(int64(lastSequence) + 1))
+
+					if err := reader.IterateFeed(author, from, numberOfValuesToReadPerIteration, func(item Item) error {
+						return nil
+					}); err != nil {
+						return errors.Wrap(err, "error iterating")
+					}
+				}
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "error calling read feeds")
+			}
+			return nil
+		},
+	}
+}
+
+func asFeedDatabaseSystem(databaseSystem DatabaseSystem) (FeedDatabaseSystem, error) {
+	feedDatabaseSystem, ok := unwrap(databaseSystem).(FeedDatabaseSystem)
+	if !ok {
+		return nil, errors.New("database system doesn't support feeds")
+	}
+	return instrumentFeeds(databaseSystem, feedDatabaseSystem), nil
+}
+
+// newAuthors deterministically generates the given number of authors so
+// that all database systems use the same ones.
+func newAuthors(n int) []Author {
+	var authors []Author
+	for i := 0; i < n; i++ {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(i))
+		authors = append(authors, sha256.Sum256(b))
+	}
+	return authors
+}
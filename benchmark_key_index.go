@@ -0,0 +1,107 @@
+package db_benchmark
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/boreq/errors"
+)
+
+func keyIndexBenchmarks() []Benchmark {
+	const numberOfAppendsToPerform = 5000
+	const numberOfValues = 100000
+	const numberOfKeysToRead = 5000
+
+	// keys appended by the setup function of read_by_hash
+	var keys []Key
+
+	return []Benchmark{
+		{
+			Name: "append_with_hash",
+			Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+				keyIndexDatabaseSystem, err := asKeyIndexDatabaseSystem(databaseSystem)
+				if err != nil {
+					return errors.Wrap(err, "error getting the key index database system")
+				}
+
+				for _, n := range batch(numberOfAppendsToPerform, databaseSystem.PreferredTransactionSize()) {
+					if err := keyIndexDatabaseSystem.UpdateWithKeys(func(updater KeyIndexUpdater) error {
+						for i := 0; i < n; i++ {
+							value := env.DataConstructor.Fn(env.Rand)
+							if _, err := updater.AppendWithKey(sha256.Sum256(value), value); err != nil {
+								return errors.Wrap(err, "error calling append with key")
+							}
+						}
+						return nil
+					}); err != nil {
+						return errors.Wrap(err, "error calling update with keys")
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name: "read_by_hash",
+			SetupFunc: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+				keyIndexDatabaseSystem, err := asKeyIndexDatabaseSystem(databaseSystem)
+				if err != nil {
+					return errors.Wrap(err, "error getting the key index database system")
+				}
+
+				keys = nil
+
+				for _, n := range batch(numberOfValues, databaseSystem.PreferredTransactionSize()) {
+					if err := keyIndexDatabaseSystem.UpdateWithKeys(func(updater KeyIndexUpdater) error {
+						for i := 0; i < n; i++ {
+							value := env.DataConstructor.Fn(env.Rand)
+							key := sha256.Sum256(value)
+							if _, err := updater.AppendWithKey(key, value); err != nil {
+								return errors.Wrap(err, "error calling append with key")
+							}
+							keys = append(keys, key)
+						}
+						return nil
+					}); err != nil {
+						return errors.Wrap(err, "error calling update with keys")
+					}
+				}
+				return nil
+			},
+			Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+				keyIndexDatabaseSystem, err := asKeyIndexDatabaseSystem(databaseSystem)
+				if err != nil {
+					return errors.Wrap(err, "error getting the key index database system")
+				}
+
+				if err := keyIndexDatabaseSystem.ReadWithKeys(func(reader KeyIndexReader) error {
+					for i := 0; i < numberOfKeysToRead; i++ {
+						seq, err := reader.GetSequence(keys[env.Rand.Intn(len(keys))])
+						if err != nil {
+							return errors.Wrap(err, "error calling get sequence")
+						}
+
+						value, err := reader.Get(seq)
+						if err != nil {
+							return errors.Wrap(err, "error calling get")
+						}
+						if len(value) == 0 {
+							b.Fatal("got an empty value")
+						}
+					}
+					return nil
+				}); err != nil {
+					return errors.Wrap(err, "error calling read with keys")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+func asKeyIndexDatabaseSystem(databaseSystem DatabaseSystem) (KeyIndexDatabaseSystem, error) {
+	keyIndexDatabaseSystem, ok := unwrap(databaseSystem).(KeyIndexDatabaseSystem)
+	if !ok {
+		return nil, errors.New("database system doesn't support the key index")
+	}
+	return instrumentKeyIndex(databaseSystem, keyIndexDatabaseSystem), nil
+}
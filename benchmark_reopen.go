@@ -0,0 +1,349 @@
+package db_benchmark
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boreq/db_benchmark/dataset"
+	"github.com/boreq/errors"
+)
+
+const (
+	ReopenWorkload = "reopen"
+
+	DefaultReopenValues = 100000
+)
+
+const (
+	// reopenChildEnv is set in the environment of a child process started
+	// by uncleanShutdownFunc, its value is the JSON encoded reopenChild.
+	reopenChildEnv = "DB_BENCHMARK_REOPEN_CHILD"
+
+	reopenChildFilled = "filled"
+)
+
+// NewReopenBenchmark returns a benchmark which appends the given number of
+// values, shuts down the database system and then measures creating it
+// again and reading the last value. If unclean is set the values are
+// appended by a child process which is killed instead of closing the
+// database system.
+func NewReopenBenchmark(numberOfValues int, unclean bool) Benchmark {
+	benchmark := Benchmark{
+		Name: fmt.Sprintf("%s_%d_values", ReopenWorkload, numberOfValues),
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			if err := databaseSystem.Read(func(reader Reader) error {
+				lastSequence, err := getLastSequence(reader)
+				if err != nil {
+					return errors.Wrap(err, "error getting last sequence")
+				}
+
+				if lastSequence != Sequence(numberOfValues-1) {
+					return fmt.Errorf("expected %d values but the last sequence is %d", numberOfValues, lastSequence)
+				}
+
+				value, err := reader.Get(lastSequence)
+				if err != nil {
+					return errors.Wrap(err, "error calling get")
+				}
+				if len(value) == 0 {
+					return errors.New("got an empty value")
+				}
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "error calling read")
+			}
+			return nil
+		},
+	}
+
+	if unclean {
+		benchmark.Name += "_unclean"
+		benchmark.ShutdownFunc = uncleanShutdownFunc(numberOfValues)
+	} else {
+		benchmark.SetupFunc = appendValuesSetupFunc(numberOfValues)
+		benchmark.ShutdownFunc = func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			return databaseSystem.Close()
+		}
+	}
+
+	return benchmark
+}
+
+// uncleanShutdownFunc returns a shutdown function which closes the empty
+// database system and then starts a child process which appends the given
+// number of values to it. The child is killed once it appended all values,
+// so the database system is never closed.
+func uncleanShutdownFunc(numberOfValues int) BenchmarkFunc {
+	return func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+		if env.TestedDatabaseSystem.Config.Type == "" {
+			return errors.New("database system wasn't created from a config so it can't be created by the child")
+		}
+
+		if err := databaseSystem.Close(); err != nil {
+			return errors.Wrap(err, "error calling close")
+		}
+
+		if err := fillInChild(env, numberOfValues); err != nil {
+			return errors.Wrap(err, "error filling the database system in a child")
+		}
+
+		return nil
+	}
+}
+
+type reopenChild struct {
+	System DatabaseSystemConfig
+	Dir    string
+}
+
+func fillInChild(env BenchmarkEnvironment, numberOfValues int) error {
+	config, err := json.Marshal(reopenChild{
+		System: env.TestedDatabaseSystem.Config,
+		Dir:    env.Dir,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the config")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "error getting the path to the executable")
+	}
+
+	cmd := exec.Command(executable)
+	cmd.Env = append(os.Environ(), reopenChildEnv+"="+string(config))
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return errors.Wrap(err, "error creating the stdin pipe")
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.Wrap(err, "error creating the stdout pipe")
+	}
+
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "error starting the child")
+	}
+
+	// the child doesn't exit on its own after appending the values
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	w := dataset.NewWriter(stdin)
+	for i := 0; i < numberOfValues; i++ {
+		if err := w.Write(env.DataConstructor.Fn(env.Rand)); err != nil {
+			return errors.Wrap(err, "error writing a value to the child")
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "error flushing the values")
+	}
+
+	if err := stdin.Close(); err != nil {
+		return errors.Wrap(err, "error closing stdin of the child")
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		return errors.Wrap(err, "error reading the output of the child")
+	}
+
+	if strings.TrimSpace(line) != reopenChildFilled {
+		return fmt.Errorf("unexpected output of the child '%s'", line)
+	}
+
+	return nil
+}
+
+// RunReopenChildIfRequested returns immediately unless the process was
+// started as a child by a benchmark which shuts down the database system
+// uncleanly. In that case it appends values read from stdin to the database
+// system and waits until it is killed, it never returns. Programs executing
+// such benchmarks must call it before doing anything else.
+func RunReopenChildIfRequested() {
+	config := os.Getenv(reopenChildEnv)
+	if config == "" {
+		return
+	}
+
+	if err := runReopenChild(config, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for {
+		time.Sleep(time.Hour)
+	}
+}
+
+func runReopenChild(config string, stdin io.Reader, stdout io.Writer) error {
+	var child reopenChild
+	if err := json.Unmarshal([]byte(config), &child); err != nil {
+		return errors.Wrap(err, "error unmarshaling the config")
+	}
+
+	testedDatabaseSystem, err := NewTestedDatabaseSystem(child.System)
+	if err != nil {
+		return errors.Wrap(err, "error creating the tested database system")
+	}
+
+	system, err := testedDatabaseSystem.DatabaseSystemConstructor(child.Dir)
+	if err != nil {
+		return errors.Wrap(err, "error creating the database system")
+	}
+
+	r := dataset.NewReader(stdin)
+
+	for done := false; !done; {
+		if err := system.Update(func(updater Updater) error {
+			for i := 0; i < system.PreferredTransactionSize(); i++ {
+				value, err := r.Next()
+				if err != nil {
+					if errors.Is(err, io.EOF) {
+						done = true
+						return nil
+					}
+					return errors.Wrap(err, "error reading the value")
+				}
+
+				if _, err := updater.Append(value); err != nil {
+					return errors.Wrap(err, "error calling append")
+				}
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error calling update")
+		}
+	}
+
+	if _, err := fmt.Fprintln(stdout, reopenChildFilled); err != nil {
+		return errors.Wrap(err, "error writing to stdout")
+	}
+
+	return nil
+}
+
+// prepareReopen closes the previously created database system, if there is
+// one, and replaces the directory with a copy of the directory left behind
+// by the shutdown function so that every reopen starts from the same state.
+func prepareReopen(previous DatabaseSystem, snapshotDir, dir string, coldCache bool) error {
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			return errors.Wrap(err, "error calling close")
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrap(err, "error removing the directory")
+	}
+
+	if err := copyDir(snapshotDir, dir); err != nil {
+		return errors.Wrap(err, "error copying the directory")
+	}
+
+	if coldCache {
+		if err := evictFromPageCache(dir); err != nil {
+			return errors.Wrap(err, "error evicting the files from the page cache")
+		}
+	}
+
+	return nil
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return errors.Wrap(err, "error getting the relative path")
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			if err := copyFile(path, target, info.Mode().Perm()); err != nil {
+				return errors.Wrapf(err, "error copying '%s'", path)
+			}
+			return nil
+		default:
+			return fmt.Errorf("unsupported file '%s'", path)
+		}
+	})
+}
+
+// copyFile copies the file skipping blocks which contain only zeros so that
+// sparse files, such as the files preallocated by badger, stay sparse.
+func copyFile(src, dst string, perm os.FileMode) error {
+	const blockSize = 64 * 1024
+
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "error opening the source file")
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return errors.Wrap(err, "error creating the destination file")
+	}
+	defer out.Close()
+
+	buf := make([]byte, blockSize)
+	zeros := make([]byte, blockSize)
+
+	var size int64
+	for {
+		n, err := io.ReadFull(in, buf)
+		if n > 0 {
+			if bytes.Equal(buf[:n], zeros[:n]) {
+				if _, err := out.Seek(int64(n), io.SeekCurrent); err != nil {
+					return errors.Wrap(err, "error seeking")
+				}
+			} else {
+				if _, err := out.Write(buf[:n]); err != nil {
+					return errors.Wrap(err, "error writing")
+				}
+			}
+			size += int64(n)
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return errors.Wrap(err, "error reading")
+		}
+	}
+
+	// seeking past the end doesn't extend the file
+	if err := out.Truncate(size); err != nil {
+		return errors.Wrap(err, "error truncating")
+	}
+
+	// writing back dirty pages would slow down creating the database system
+	if err := out.Sync(); err != nil {
+		return errors.Wrap(err, "error syncing")
+	}
+
+	return out.Close()
+}
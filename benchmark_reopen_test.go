@@ -0,0 +1,94 @@
+package db_benchmark
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReopenBenchmark(t *testing.T) {
+	const numberOfValues = 1000
+
+	systems, err := Matrix{
+		Systems: []MatrixSystem{
+			{Type: BoltDatabaseSystemType},
+			{Type: BadgerDatabaseSystemType},
+			{Type: MargaretDatabaseSystemType},
+		},
+	}.DatabaseSystems()
+	require.NoError(t, err)
+
+	for _, system := range systems {
+		for _, unclean := range []bool{false, true} {
+			benchmark := NewReopenBenchmark(numberOfValues, unclean)
+
+			t.Run(system.Name+"/"+benchmark.Name, func(t *testing.T) {
+				snapshotDir := fixtures.Directory(t, "")
+
+				env := BenchmarkEnvironment{
+					TestedDatabaseSystem: system,
+					DataConstructor:      RandomDataConstructor(),
+					Dir:                  snapshotDir,
+					Rand:                 rand.New(rand.NewSource(1)),
+				}
+
+				databaseSystem, err := system.DatabaseSystemConstructor(snapshotDir)
+				require.NoError(t, err)
+
+				if benchmark.SetupFunc != nil {
+					require.NoError(t, benchmark.SetupFunc(nil, databaseSystem, env))
+				}
+
+				require.NoError(t, benchmark.ShutdownFunc(nil, databaseSystem, env))
+
+				env.Dir = fixtures.Directory(t, "")
+				databaseSystem = nil
+
+				// every reopen has to start from the state left behind by
+				// the shutdown
+				for i := 0; i < 2; i++ {
+					require.NoError(t, prepareReopen(databaseSystem, snapshotDir, env.Dir, false))
+
+					databaseSystem, err = system.DatabaseSystemConstructor(env.Dir)
+					require.NoError(t, err)
+
+					require.NoError(t, benchmark.Func(nil, databaseSystem, env))
+				}
+
+				require.NoError(t, databaseSystem.Close())
+			})
+		}
+	}
+}
+
+func TestCopyDirKeepsFilesSparse(t *testing.T) {
+	src := fixtures.Directory(t, "")
+	dst := filepath.Join(fixtures.Directory(t, ""), "copy")
+
+	require.NoError(t, os.Mkdir(filepath.Join(src, "nested"), 0700))
+
+	data := append(make([]byte, 1024*1024), []byte("value")...)
+	require.NoError(t, os.WriteFile(filepath.Join(src, "nested", "file"), data, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "empty"), nil, 0600))
+
+	require.NoError(t, copyDir(src, dst))
+
+	copied, err := os.ReadFile(filepath.Join(dst, "nested", "file"))
+	require.NoError(t, err)
+	require.Equal(t, data, copied)
+
+	copied, err = os.ReadFile(filepath.Join(dst, "empty"))
+	require.NoError(t, err)
+	require.Empty(t, copied)
+
+	if runtime.GOOS == "linux" {
+		usage, err := dirDiskUsage(dst)
+		require.NoError(t, err)
+		require.Less(t, usage, int64(len(data)))
+	}
+}
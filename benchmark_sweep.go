@@ -0,0 +1,212 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boreq/errors"
+)
+
+// Operations measured by RunSweep after each step.
+const (
+	SweepOperationAppend     = "append"
+	SweepOperationReadRandom = "read_random"
+)
+
+// SweepStepFunc is called by RunSweep for each measurement. Name identifies
+// the step and the operation, for example "size_1000000/append". The
+// provided function should be executed as a benchmark.
+type SweepStepFunc func(name string, fn func(b *testing.B) error) error
+
+const (
+	sweepLogDir    = "log"
+	sweepAppendDir = "append"
+)
+
+// RunSweep creates a database system in the given directory and grows its
+// log in steps to each of the given sizes. Once the log reaches each size
+// runStep is called for every sweep operation. Appends are measured on a
+// copy of the log so that only growLog changes the size of the log. The copy
+// is made once per step, when the step function is first called, as copying
+// a large log every time testing.Benchmark calls the function while it
+// determines b.N would take longer than the measurements. The values
+// appended by the earlier calls remain in the copy, so the later calls
+// measure a log which is larger than the step by the sum of the earlier b.N.
+func RunSweep(dir string, testedDatabaseSystem TestedDatabaseSystem, dataConstructor DataConstructor, seed int64, sizes []int, runStep SweepStepFunc) error {
+	logDir := filepath.Join(dir, sweepLogDir)
+	appendDir := filepath.Join(dir, sweepAppendDir)
+
+	if err := os.Mkdir(logDir, 0700); err != nil {
+		return errors.Wrap(err, "error creating the log directory")
+	}
+
+	system, err := testedDatabaseSystem.DatabaseSystemConstructor(logDir)
+	if err != nil {
+		return errors.Wrap(err, "error creating the database system")
+	}
+
+	env := BenchmarkEnvironment{
+		TestedDatabaseSystem: testedDatabaseSystem,
+		DataConstructor:      dataConstructor,
+		Dir:                  logDir,
+		Rand:                 rand.New(rand.NewSource(seed)),
+	}
+
+	if err := dataConstructor.reset(); err != nil {
+		return errors.Wrap(err, "error resetting the data constructor")
+	}
+
+	for _, size := range sizes {
+		if err := growLog(system, env, size); err != nil {
+			return errors.Wrapf(err, "error growing the log to %d values", size)
+		}
+
+		if err := dataConstructor.err(); err != nil {
+			return errors.Wrap(err, "data constructor returned an error")
+		}
+
+		if err := system.Close(); err != nil {
+			return errors.Wrap(err, "error calling close")
+		}
+
+		appendEnv := env
+		appendEnv.Dir = appendDir
+
+		var appendSystem DatabaseSystem
+		name := fmt.Sprintf("size_%d/%s", size, SweepOperationAppend)
+		if err := runStep(name, func(b *testing.B) error {
+			if appendSystem == nil {
+				if err := prepareReopen(nil, logDir, appendDir, false); err != nil {
+					return errors.Wrap(err, "error copying the log")
+				}
+
+				appendSystem, err = testedDatabaseSystem.DatabaseSystemConstructor(appendDir)
+				if err != nil {
+					return errors.Wrap(err, "error creating the database system")
+				}
+			}
+
+			return measureSweepStep(b, appendSystem, appendEnv, sweepAppend)
+		}); err != nil {
+			return errors.Wrapf(err, "error running step '%s'", name)
+		}
+
+		if appendSystem != nil {
+			if err := appendSystem.Close(); err != nil {
+				return errors.Wrap(err, "error calling close")
+			}
+		}
+
+		if err := os.RemoveAll(appendDir); err != nil {
+			return errors.Wrap(err, "error removing the copy of the log")
+		}
+
+		system, err = testedDatabaseSystem.DatabaseSystemConstructor(logDir)
+		if err != nil {
+			return errors.Wrap(err, "error creating the database system")
+		}
+
+		name = fmt.Sprintf("size_%d/%s", size, SweepOperationReadRandom)
+		if err := runStep(name, func(b *testing.B) error {
+			return measureSweepStep(b, system, env, sweepReadRandom)
+		}); err != nil {
+			return errors.Wrapf(err, "error running step '%s'", name)
+		}
+	}
+
+	if err := system.Close(); err != nil {
+		return errors.Wrap(err, "error calling close")
+	}
+
+	return nil
+}
+
+func measureSweepStep(b *testing.B, system DatabaseSystem, env BenchmarkEnvironment, fn BenchmarkFunc) error {
+	instrumentedSystem := NewInstrumentedDatabaseSystem(system)
+
+	b.ResetTimer()
+
+	if err := fn(b, instrumentedSystem, env); err != nil {
+		return errors.Wrap(err, "operation returned an error")
+	}
+
+	if err := env.DataConstructor.err(); err != nil {
+		return errors.Wrap(err, "data constructor returned an error")
+	}
+
+	if err := system.Sync(); err != nil {
+		return errors.Wrap(err, "error calling sync")
+	}
+
+	b.StopTimer()
+
+	reportLatencies(b, instrumentedSystem.Latencies())
+	return nil
+}
+
+// growLog appends values until the log contains at least the given number
+// of values.
+func growLog(databaseSystem DatabaseSystem, env BenchmarkEnvironment, size int) error {
+	var currentSize int
+	if err := databaseSystem.Read(func(reader Reader) error {
+		lastSequence, ok, err := reader.LastSequence()
+		if err != nil {
+			return errors.Wrap(err, "error calling last sequence")
+		}
+
+		if ok {
+			currentSize = int(lastSequence) + 1
+		}
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "error calling read")
+	}
+
+	if currentSize >= size {
+		return nil
+	}
+
+	return appendValuesSetupFunc(size-currentSize)(nil, databaseSystem, env)
+}
+
+// sweepAppend appends b.N values.
+func sweepAppend(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+	for _, n := range batch(b.N, databaseSystem.PreferredTransactionSize()) {
+		if err := databaseSystem.Update(func(updater Updater) error {
+			for i := 0; i < n; i++ {
+				if _, err := updater.Append(env.DataConstructor.Fn(env.Rand)); err != nil {
+					return errors.Wrap(err, "error calling append")
+				}
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error calling update")
+		}
+	}
+	return nil
+}
+
+// sweepReadRandom gets b.N values with random sequences.
+func sweepReadRandom(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+	for _, n := range batch(b.N, databaseSystem.PreferredTransactionSize()) {
+		if err := databaseSystem.Read(func(reader Reader) error {
+			lastSequence, err := getLastSequence(reader)
+			if err != nil {
+				return errors.Wrap(err, "error getting last sequence")
+			}
+
+			for i := 0; i < n; i++ {
+				if _, err := reader.Get(Sequence(env.Rand.Int63n(int64(lastSequence) + 1))); err != nil {
+					return errors.Wrap(err, "error calling get")
+				}
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error calling read")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package db_benchmark
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweepDoesNotGrowLogWhenMeasuringAppends(t *testing.T) {
+	system, err := NewTestedDatabaseSystem(DatabaseSystemConfig{
+		Type:            BoltDatabaseSystemType,
+		Codec:           CodecNone,
+		TransactionSize: DefaultTransactionSize,
+		Durability:      DurabilityNone,
+	})
+	require.NoError(t, err)
+
+	dataConstructor := DataConstructors()[0]
+	dir := fixtures.Directory(t, "")
+
+	var steps []string
+	err = RunSweep(dir, system, dataConstructor, 1, []int{10, 20}, func(name string, fn func(b *testing.B) error) error {
+		steps = append(steps, name)
+		if !strings.HasSuffix(name, SweepOperationAppend) {
+			return nil
+		}
+
+		var stepErr error
+		result := testing.Benchmark(func(b *testing.B) {
+			stepErr = fn(b)
+		})
+		require.NoError(t, stepErr)
+		require.Positive(t, result.N)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"size_10/append",
+		"size_10/read_random",
+		"size_20/append",
+		"size_20/read_random",
+	}, steps)
+
+	databaseSystem, err := system.DatabaseSystemConstructor(filepath.Join(dir, sweepLogDir))
+	require.NoError(t, err)
+	defer databaseSystem.Close()
+
+	err = databaseSystem.Read(func(reader Reader) error {
+		lastSequence, err := getLastSequence(reader)
+		require.NoError(t, err)
+		require.Equal(t, Sequence(19), lastSequence)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,87 @@
+package db_benchmark
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReopenWithColdCache(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("evicting files from the page cache is only supported on linux")
+	}
+
+	systems, err := DefaultMatrix().DatabaseSystems()
+	require.NoError(t, err)
+
+	for _, system := range systems {
+		t.Run(system.Name, func(t *testing.T) {
+			dir := fixtures.Directory(t, "")
+
+			databaseSystem, err := system.DatabaseSystemConstructor(dir)
+			require.NoError(t, err)
+
+			value := []byte("value")
+
+			err = databaseSystem.Update(func(updater Updater) error {
+				_, err := updater.Append(value)
+				return err
+			})
+			require.NoError(t, err)
+
+			databaseSystem, err = reopenWithColdCache(system, databaseSystem, dir)
+			require.NoError(t, err)
+
+			err = databaseSystem.Read(func(reader Reader) error {
+				v, err := reader.Get(0)
+				require.NoError(t, err)
+				require.Equal(t, value, v)
+				return nil
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, databaseSystem.Close())
+		})
+	}
+}
+
+func TestRecreate(t *testing.T) {
+	systems, err := Matrix{
+		Systems: []MatrixSystem{
+			{Type: BoltDatabaseSystemType},
+			{Type: BadgerDatabaseSystemType},
+			{Type: MargaretDatabaseSystemType},
+		},
+	}.DatabaseSystems()
+	require.NoError(t, err)
+
+	for _, system := range systems {
+		t.Run(system.Name, func(t *testing.T) {
+			dir := fixtures.Directory(t, "")
+
+			databaseSystem, err := system.DatabaseSystemConstructor(dir)
+			require.NoError(t, err)
+
+			err = databaseSystem.Update(func(updater Updater) error {
+				_, err := updater.Append([]byte("value"))
+				return err
+			})
+			require.NoError(t, err)
+
+			databaseSystem, err = recreate(system, databaseSystem, dir)
+			require.NoError(t, err)
+
+			err = databaseSystem.Read(func(reader Reader) error {
+				_, ok, err := reader.LastSequence()
+				require.NoError(t, err)
+				require.False(t, ok, "recreated database system should be empty")
+				return nil
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, databaseSystem.Close())
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/boreq/db_benchmark/workload"
+	"github.com/boreq/errors"
+)
+
+const YCSBWorkload = "ycsb"
+
+const (
+	ycsbNumberOfValues     = 100000
+	ycsbNumberOfOperations = 1000
+)
+
+// NewYCSBBenchmark returns a benchmark which executes the operations of the
+// YCSB workload against a log filled with values. Every operation is
+// performed in a separate transaction:
+//
+//   - read gets the value with a sequence selected by the key chooser,
+//   - update and insert append a value as values can't be modified,
+//   - scan iterates over values starting at a sequence selected by the key
+//     chooser,
+//   - read-modify-write gets the value with a sequence selected by the key
+//     chooser and then appends a value.
+//
+// The operations, the sequences which they access and the values which they
+// append are selected before the operations are executed so that generating
+// them isn't measured.
+//
+// The throughput of each operation is reported using units such as
+// "read_ops/s" and the latencies of each operation are reported in the same
+// way as the latencies of calls to the database system e.g. "read_p99_ns".
+func NewYCSBBenchmark(w workload.Workload, keyChooser KeyChooser) (Benchmark, error) {
+	if err := w.Validate(); err != nil {
+		return Benchmark{}, errors.Wrap(err, "invalid workload")
+	}
+
+	return Benchmark{
+		Name:      fmt.Sprintf("%s_%s_%s", YCSBWorkload, w.Name, keyChooser.Name()),
+		SetupFunc: appendValuesSetupFunc(ycsbNumberOfValues),
+		Func: func(b *testing.B, databaseSystem DatabaseSystem, env BenchmarkEnvironment) error {
+			var lastSequence Sequence
+			if err := databaseSystem.Read(func(reader Reader) error {
+				v, err := getLastSequence(reader)
+				if err != nil {
+					return errors.Wrap(err, "error getting last sequence")
+				}
+				lastSequence = v
+				return nil
+			}); err != nil {
+				return errors.Wrap(err, "error calling read")
+			}
+
+			// choosing the sequences and generating the values isn't
+			// measured
+			b.StopTimer()
+			operations := planYCSBOperations(w, keyChooser, env, lastSequence)
+			b.StartTimer()
+
+			counts := make(map[workload.Operation]int)
+			start := time.Now()
+
+			for _, operation := range operations {
+				operationStart := time.Now()
+
+				switch operation.operation {
+				case workload.Read:
+					if err := ycsbRead(databaseSystem, operation.sequence); err != nil {
+						return errors.Wrap(err, "error performing read")
+					}
+				case workload.Update, workload.Insert:
+					if _, err := ycsbAppend(databaseSystem, operation.value); err != nil {
+						return errors.Wrapf(err, "error performing %s", operation.operation)
+					}
+				case workload.Scan:
+					if err := ycsbScan(databaseSystem, operation.sequence, operation.scanLength); err != nil {
+						return errors.Wrap(err, "error performing scan")
+					}
+				case workload.ReadModifyWrite:
+					if err := ycsbRead(databaseSystem, operation.sequence); err != nil {
+						return errors.Wrap(err, "error performing read-modify-write")
+					}
+
+					if _, err := ycsbAppend(databaseSystem, operation.value); err != nil {
+						return errors.Wrap(err, "error performing read-modify-write")
+					}
+				default:
+					return fmt.Errorf("unknown operation '%s'", operation.operation)
+				}
+
+				recordLatency(databaseSystem, string(operation.operation), operationStart)
+				counts[operation.operation]++
+			}
+
+			elapsed := time.Since(start).Seconds()
+
+			for _, operation := range workload.Operations {
+				if w.Percentage(operation) > 0 {
+					b.ReportMetric(float64(counts[operation])/elapsed, fmt.Sprintf("%s_ops/s", operation))
+				}
+			}
+			b.ReportMetric(ycsbNumberOfOperations/elapsed, "ops/s")
+
+			return nil
+		},
+	}, nil
+}
+
+type ycsbOperation struct {
+	operation  workload.Operation
+	sequence   Sequence
+	scanLength int
+	value      []byte
+}
+
+// planYCSBOperations selects the operations, the sequences which they access
+// and the values which they append. Every append increments the last
+// sequence so that the key choosers which prefer recent values, such as the
+// latest key chooser, can choose the values appended by earlier operations.
+func planYCSBOperations(w workload.Workload, keyChooser KeyChooser, env BenchmarkEnvironment, lastSequence Sequence) []ycsbOperation {
+	operations := make([]ycsbOperation, ycsbNumberOfOperations)
+
+	for i := range operations {
+		operation := ycsbOperation{
+			operation: w.NextOperation(env.Rand),
+		}
+
+		switch operation.operation {
+		case workload.Read, workload.Scan, workload.ReadModifyWrite:
+			operation.sequence = keyChooser.Choose(env.Rand, lastSequence)
+		}
+
+		switch operation.operation {
+		case workload.Update, workload.Insert, workload.ReadModifyWrite:
+			operation.value = env.DataConstructor.Fn(env.Rand)
+			lastSequence++
+		case workload.Scan:
+			operation.scanLength = w.ScanLength(env.Rand)
+		}
+
+		operations[i] = operation
+	}
+
+	return operations
+}
+
+// NewRequestDistributionKeyChooser returns the key chooser which implements
+// the request distribution of a YCSB workload.
+func NewRequestDistributionKeyChooser(distribution string) (KeyChooser, error) {
+	switch distribution {
+	case workload.DistributionUniform:
+		return NewUniformKeyChooser(), nil
+	case workload.DistributionZipfian:
+		return NewZipfianKeyChooser(DefaultZipfianTheta)
+	case workload.DistributionLatest:
+		return NewLatestKeyChooser(DefaultZipfianTheta)
+	default:
+		return nil, fmt.Errorf("unknown request distribution '%s'", distribution)
+	}
+}
+
+func ycsbRead(databaseSystem DatabaseSystem, sequence Sequence) error {
+	return databaseSystem.Read(func(reader Reader) error {
+		value, err := reader.Get(sequence)
+		if err != nil {
+			return errors.Wrap(err, "error calling get")
+		}
+		if len(value) == 0 {
+			return errors.New("got an empty value")
+		}
+		return nil
+	})
+}
+
+func ycsbAppend(databaseSystem DatabaseSystem, value []byte) (Sequence, error) {
+	var sequence Sequence
+	if err := databaseSystem.Update(func(updater Updater) error {
+		v, err := updater.Append(value)
+		if err != nil {
+			return errors.Wrap(err, "error calling append")
+		}
+		sequence = v
+		return nil
+	}); err != nil {
+		return 0, errors.Wrap(err, "error calling update")
+	}
+	return sequence, nil
+}
+
+func ycsbScan(databaseSystem DatabaseSystem, start Sequence, length int) error {
+	return databaseSystem.Read(func(reader Reader) error {
+		if err := reader.Iterate(start, length, func(item Item) error {
+			if len(item.Value) == 0 {
+				return errors.New("got an empty value")
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error calling iterate")
+		}
+		return nil
+	})
+}
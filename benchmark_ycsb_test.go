@@ -0,0 +1,83 @@
+package db_benchmark
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/boreq/db_benchmark/workload"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYCSBBenchmarkReportsThroughputAndLatencies(t *testing.T) {
+	system, err := NewTestedDatabaseSystem(DatabaseSystemConfig{
+		Type:            BoltDatabaseSystemType,
+		Codec:           CodecNone,
+		TransactionSize: DefaultTransactionSize,
+		Durability:      DurabilityNone,
+	})
+	require.NoError(t, err)
+
+	for _, coreWorkload := range []string{"a", "e"} {
+		t.Run(coreWorkload, func(t *testing.T) {
+			w, err := workload.CoreWorkload(coreWorkload)
+			require.NoError(t, err)
+
+			keyChooser, err := NewRequestDistributionKeyChooser(w.RequestDistribution)
+			require.NoError(t, err)
+
+			benchmark, err := NewYCSBBenchmark(w, keyChooser)
+			require.NoError(t, err)
+
+			var benchmarkErr error
+			result := testing.Benchmark(func(b *testing.B) {
+				benchmarkErr = RunBenchmark(b, system, StorageSystem{}, DataConstructors()[0], benchmark, 1)
+			})
+			require.NoError(t, benchmarkErr)
+
+			require.Positive(t, result.Extra["ops/s"])
+
+			for _, operation := range workload.Operations {
+				if w.Percentage(operation) == 0 {
+					require.NotContains(t, result.Extra, string(operation)+"_ops/s")
+					continue
+				}
+
+				require.Positive(t, result.Extra[string(operation)+"_ops/s"], operation)
+				require.Contains(t, result.Extra, LatencyUnit(string(operation), "p50"), operation)
+			}
+		})
+	}
+}
+
+func TestPlanYCSBOperations(t *testing.T) {
+	w, err := workload.CoreWorkload("d")
+	require.NoError(t, err)
+
+	keyChooser, err := NewLatestKeyChooser(DefaultZipfianTheta)
+	require.NoError(t, err)
+
+	env := BenchmarkEnvironment{
+		DataConstructor: DataConstructors()[0],
+		Rand:            rand.New(rand.NewSource(1)),
+	}
+
+	const lastSequence = 10
+
+	operations := planYCSBOperations(w, keyChooser, env, lastSequence)
+	require.Len(t, operations, ycsbNumberOfOperations)
+
+	appended := 0
+	for _, operation := range operations {
+		switch operation.operation {
+		case workload.Read:
+			require.LessOrEqual(t, operation.sequence, Sequence(lastSequence+appended))
+			require.Nil(t, operation.value)
+		case workload.Insert:
+			require.NotEmpty(t, operation.value)
+			appended++
+		default:
+			t.Fatalf("unexpected operation '%s'", operation.operation)
+		}
+	}
+	require.Positive(t, appended)
+}
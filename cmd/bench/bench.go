@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	dbbenchmark "github.com/boreq/db_benchmark"
+	"github.com/boreq/db_benchmark/cmd/internal/cmdutil"
+	"github.com/boreq/errors"
+)
+
+func main() {
+	dbbenchmark.RunReopenChildIfRequested()
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	matrixFile := flags.String("matrix", "", "path to the file describing the benchmark matrix (default: all database systems, data constructors and benchmarks)")
+	systemNames := flags.String("system", "", "comma separated names of database systems to benchmark (default: all)")
+	storagePath := flags.String("storage", "", "path to the directory in which the databases will be created, overrides the storage listed in the matrix (default: os.TempDir)")
+	storageName := flags.String("storage-name", "default_storage", "name of the storage used in the benchmark names")
+	dataConstructorNames := flags.String("data", "", "comma separated names of data constructors (default: all)")
+	benchmarkNames := flags.String("benchmark", "", "comma separated names of benchmarks (default: all)")
+	duration := flags.String("duration", "1s", "run each benchmark for this duration or number of iterations e.g. 10s or 100x")
+	size := flags.Bool("size", false, "additionally run the size benchmark")
+	sweep := flags.Bool("sweep", false, "additionally run the dataset size sweep described in the matrix")
+	seed := flags.Int64("seed", 0, "seed used for all sources of randomness, overrides the seed listed in the matrix (default: derived from the current time)")
+	list := flags.Bool("list", false, "list available database systems, data constructors and benchmarks and exit")
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return errors.Wrap(err, "error parsing flags")
+	}
+
+	matrix := dbbenchmark.DefaultMatrix()
+	if *matrixFile != "" {
+		loadedMatrix, err := dbbenchmark.LoadMatrix(*matrixFile)
+		if err != nil {
+			return errors.Wrap(err, "error loading the matrix")
+		}
+		matrix = loadedMatrix
+	}
+
+	if *matrixFile == "" || cmdutil.IsFlagSet(flags, "storage") || cmdutil.IsFlagSet(flags, "storage-name") {
+		matrix.Storage = []dbbenchmark.MatrixStorage{
+			{
+				Name: *storageName,
+				Path: *storagePath,
+			},
+		}
+	}
+
+	if *list {
+		return printAvailable(matrix)
+	}
+
+	testing.Init()
+	if err := flag.Set("test.benchtime", *duration); err != nil {
+		return errors.Wrap(err, "error setting the benchmark duration")
+	}
+
+	allSystems, err := matrix.DatabaseSystems()
+	if err != nil {
+		return errors.Wrap(err, "error getting database systems")
+	}
+
+	systems, err := selectDatabaseSystems(allSystems, *systemNames)
+	if err != nil {
+		return errors.Wrap(err, "error selecting database systems")
+	}
+
+	allDataConstructors, err := matrix.DataConstructors()
+	if err != nil {
+		return errors.Wrap(err, "error getting data constructors")
+	}
+	defer dbbenchmark.CloseDataConstructors(allDataConstructors)
+
+	dataConstructors, err := selectDataConstructors(allDataConstructors, *dataConstructorNames)
+	if err != nil {
+		return errors.Wrap(err, "error selecting data constructors")
+	}
+
+	allBenchmarks, err := matrix.Benchmarks()
+	if err != nil {
+		return errors.Wrap(err, "error getting benchmarks")
+	}
+
+	benchmarks, err := selectBenchmarks(allBenchmarks, *benchmarkNames)
+	if err != nil {
+		return errors.Wrap(err, "error selecting benchmarks")
+	}
+
+	storageSystems, err := matrix.StorageSystems()
+	if err != nil {
+		return errors.Wrap(err, "error getting storage systems")
+	}
+
+	runSeed := dbbenchmark.NewSeed()
+	if cmdutil.IsFlagSet(flags, "seed") {
+		runSeed = *seed
+	} else if matrix.Seed != nil {
+		runSeed = *matrix.Seed
+	}
+
+	printHeader(runSeed)
+
+	for _, system := range systems {
+		for _, storageSystem := range storageSystems {
+			for _, dataConstructor := range dataConstructors {
+				for _, benchmark := range benchmarks {
+					name := fmt.Sprintf("BenchmarkPerformance/%s/%s/%s/%s", system.Name, storageSystem.Name, dataConstructor.Name, benchmark.Name)
+					if err := runAndPrint(name, func(b *testing.B) error {
+						return dbbenchmark.RunBenchmark(b, system, storageSystem, dataConstructor, benchmark, runSeed)
+					}); err != nil {
+						return errors.Wrapf(err, "benchmark '%s' failed", name)
+					}
+				}
+			}
+		}
+	}
+
+	if *size {
+		for _, system := range systems {
+			for _, dataConstructor := range dataConstructors {
+				name := fmt.Sprintf("BenchmarkSize/%s/%s", system.Name, dataConstructor.Name)
+				if err := runAndPrint(name, func(b *testing.B) error {
+					return dbbenchmark.RunSizeBenchmark(b, system, dataConstructor, runSeed)
+				}); err != nil {
+					return errors.Wrapf(err, "benchmark '%s' failed", name)
+				}
+			}
+		}
+	}
+
+	if *sweep {
+		sizes, err := matrix.SweepSizes()
+		if err != nil {
+			return errors.Wrap(err, "error getting sweep sizes")
+		}
+
+		if sizes == nil {
+			return errors.New("matrix doesn't define a sweep")
+		}
+
+		for _, system := range systems {
+			for _, storageSystem := range storageSystems {
+				for _, dataConstructor := range dataConstructors {
+					name := fmt.Sprintf("BenchmarkSweep/%s/%s/%s", system.Name, storageSystem.Name, dataConstructor.Name)
+					if err := runSweep(name, system, storageSystem, dataConstructor, runSeed, sizes); err != nil {
+						return errors.Wrapf(err, "sweep '%s' failed", name)
+					}
+				}
+			}
+		}
+	}
+
+	fmt.Println("PASS")
+	return nil
+}
+
+func runSweep(name string, system dbbenchmark.TestedDatabaseSystem, storageSystem dbbenchmark.StorageSystem, dataConstructor dbbenchmark.DataConstructor, seed int64, sizes []int) error {
+	dir, err := os.MkdirTemp(storageSystem.Path, "db-benchmark")
+	if err != nil {
+		return errors.Wrap(err, "error creating a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	return dbbenchmark.RunSweep(dir, system, dataConstructor, seed, sizes, func(stepName string, fn func(b *testing.B) error) error {
+		return runAndPrint(fmt.Sprintf("%s/%s", name, stepName), fn)
+	})
+}
+
+// runAndPrint runs the benchmark and prints the result in the same format
+// as the one used by go test so that it can be parsed by the report tool.
+func runAndPrint(name string, fn func(b *testing.B) error) error {
+	var benchmarkErr error
+	var skipped bool
+
+	result := testing.Benchmark(func(b *testing.B) {
+		defer func() {
+			skipped = b.Skipped()
+		}()
+
+		if err := fn(b); err != nil {
+			benchmarkErr = err
+			b.FailNow()
+		}
+	})
+
+	if benchmarkErr != nil {
+		return benchmarkErr
+	}
+
+	if skipped {
+		fmt.Fprintf(os.Stderr, "%s skipped\n", name)
+		return nil
+	}
+
+	if result.N == 0 {
+		return errors.New("benchmark failed")
+	}
+
+	fmt.Printf("%s%s\t%s\n", name, procsSuffix(), result.String())
+	return nil
+}
+
+func printHeader(seed int64) {
+	fmt.Printf("goos: %s\n", runtime.GOOS)
+	fmt.Printf("goarch: %s\n", runtime.GOARCH)
+	fmt.Printf("pkg: %s\n", "github.com/boreq/db_benchmark")
+	fmt.Printf("cpu: %s\n", cpuName())
+	fmt.Printf("seed: %d\n", seed)
+}
+
+func printAvailable(matrix dbbenchmark.Matrix) error {
+	systems, err := matrix.DatabaseSystems()
+	if err != nil {
+		return errors.Wrap(err, "error getting database systems")
+	}
+
+	storageSystems, err := matrix.StorageSystems()
+	if err != nil {
+		return errors.Wrap(err, "error getting storage systems")
+	}
+
+	dataConstructors, err := matrix.DataConstructors()
+	if err != nil {
+		return errors.Wrap(err, "error getting data constructors")
+	}
+	defer dbbenchmark.CloseDataConstructors(dataConstructors)
+
+	benchmarks, err := matrix.Benchmarks()
+	if err != nil {
+		return errors.Wrap(err, "error getting benchmarks")
+	}
+
+	fmt.Println("database systems:")
+	for _, v := range systems {
+		fmt.Printf("  %s\n", v.Name)
+	}
+
+	fmt.Println("storage systems:")
+	for _, v := range storageSystems {
+		fmt.Printf("  %s (%s)\n", v.Name, v.Path)
+	}
+
+	fmt.Println("data constructors:")
+	for _, v := range dataConstructors {
+		fmt.Printf("  %s\n", v.Name)
+	}
+
+	fmt.Println("benchmarks:")
+	for _, v := range benchmarks {
+		fmt.Printf("  %s\n", v.Name)
+	}
+
+	return nil
+}
+
+func selectDatabaseSystems(all []dbbenchmark.TestedDatabaseSystem, names string) ([]dbbenchmark.TestedDatabaseSystem, error) {
+	if names == "" {
+		return all, nil
+	}
+
+	var v []dbbenchmark.TestedDatabaseSystem
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, system := range all {
+			if system.Name == name {
+				v = append(v, system)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown database system '%s'", name)
+		}
+	}
+	return v, nil
+}
+
+func selectDataConstructors(all []dbbenchmark.DataConstructor, names string) ([]dbbenchmark.DataConstructor, error) {
+	if names == "" {
+		return all, nil
+	}
+
+	var v []dbbenchmark.DataConstructor
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, dataConstructor := range all {
+			if dataConstructor.Name == name {
+				v = append(v, dataConstructor)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown data constructor '%s'", name)
+		}
+	}
+	return v, nil
+}
+
+func selectBenchmarks(all []dbbenchmark.Benchmark, names string) ([]dbbenchmark.Benchmark, error) {
+	if names == "" {
+		return all, nil
+	}
+
+	var v []dbbenchmark.Benchmark
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, benchmark := range all {
+			if benchmark.Name == name {
+				v = append(v, benchmark)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown benchmark '%s'", name)
+		}
+	}
+	return v, nil
+}
+
+// procsSuffix mimics the suffix appended to the benchmark names by go test.
+func procsSuffix() string {
+	if n := runtime.GOMAXPROCS(0); n != 1 {
+		return fmt.Sprintf("-%d", n)
+	}
+	return ""
+}
+
+func cpuName() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return "unknown"
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		key, value, ok := strings.Cut(scan.Text(), ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return "unknown"
+}
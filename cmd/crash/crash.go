@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	dbbenchmark "github.com/boreq/db_benchmark"
+	"github.com/boreq/db_benchmark/cmd/internal/cmdutil"
+	"github.com/boreq/db_benchmark/crash"
+	"github.com/boreq/errors"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	matrixFile := flags.String("matrix", "", "path to the file describing the benchmark matrix, only the database systems are used (default: all database systems)")
+	systemNames := flags.String("system", "", "comma separated names of database systems to test (default: all)")
+	storagePath := flags.String("storage", "", "path to the directory in which the databases will be created (default: os.TempDir)")
+	rounds := flags.Int("rounds", crash.DefaultRounds, "number of times the appending process is killed per database system")
+	maxDelay := flags.Duration("max-delay", crash.DefaultMaxDelay, "maximum time for which the appending process runs before it is killed")
+	seed := flags.Int64("seed", 0, "seed used to generate values and delays (default: derived from the current time)")
+	verbose := flags.Bool("v", false, "print the detected corruption")
+	childDir := flags.String("child", "", "internal: append to the database system in this directory until killed")
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return errors.Wrap(err, "error parsing flags")
+	}
+
+	matrix := dbbenchmark.DefaultMatrix()
+	if *matrixFile != "" {
+		loadedMatrix, err := dbbenchmark.LoadMatrix(*matrixFile)
+		if err != nil {
+			return errors.Wrap(err, "error loading the matrix")
+		}
+		matrix = loadedMatrix
+	}
+
+	allSystems, err := matrix.DatabaseSystems()
+	if err != nil {
+		return errors.Wrap(err, "error getting database systems")
+	}
+
+	systems, err := selectDatabaseSystems(allSystems, *systemNames)
+	if err != nil {
+		return errors.Wrap(err, "error selecting database systems")
+	}
+
+	if *childDir != "" {
+		if len(systems) != 1 {
+			return errors.New("child requires exactly one database system")
+		}
+		return crash.RunChild(systems[0], *childDir, *seed, os.Stdout)
+	}
+
+	runSeed := dbbenchmark.NewSeed()
+	if cmdutil.IsFlagSet(flags, "seed") {
+		runSeed = *seed
+	} else if matrix.Seed != nil {
+		runSeed = *matrix.Seed
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "error getting the path to the executable")
+	}
+
+	fmt.Printf("seed: %d\n", runSeed)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "system\trounds\tacknowledged writes\tlost acknowledged writes\tcorruption detected")
+
+	for _, system := range systems {
+		result, err := runSystem(executable, *matrixFile, system, *storagePath, crash.Config{
+			Rounds:   *rounds,
+			MaxDelay: *maxDelay,
+			Seed:     runSeed,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error testing '%s'", system.Name)
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", system.Name, result.Rounds, result.AcknowledgedWrites, result.LostAcknowledgedWrites, result.CorruptionDetected)
+
+		if *verbose {
+			for _, corruption := range result.Corruptions {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", system.Name, corruption)
+			}
+		}
+	}
+
+	return w.Flush()
+}
+
+func runSystem(executable, matrixFile string, system dbbenchmark.TestedDatabaseSystem, storagePath string, config crash.Config) (crash.Result, error) {
+	dir, err := os.MkdirTemp(storagePath, "db-benchmark-crash")
+	if err != nil {
+		return crash.Result{}, errors.Wrap(err, "error creating a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	return crash.Run(system, dir, config, func(dir string, seed int64) *exec.Cmd {
+		args := []string{
+			"-system", system.Name,
+			"-seed", strconv.FormatInt(seed, 10),
+			"-child", dir,
+		}
+		if matrixFile != "" {
+			args = append(args, "-matrix", matrixFile)
+		}
+		return exec.Command(executable, args...)
+	})
+}
+
+func selectDatabaseSystems(all []dbbenchmark.TestedDatabaseSystem, names string) ([]dbbenchmark.TestedDatabaseSystem, error) {
+	if names == "" {
+		return all, nil
+	}
+
+	var v []dbbenchmark.TestedDatabaseSystem
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, system := range all {
+			if system.Name == name {
+				v = append(v, system)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown database system '%s'", name)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	dbbenchmark "github.com/boreq/db_benchmark"
+	"github.com/boreq/db_benchmark/cmd/internal/cmdutil"
+	"github.com/boreq/db_benchmark/dataset"
+	"github.com/boreq/errors"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	output := flags.String("output", "", "path to the created dataset file, the metadata is written to the same path with the .json extension appended")
+	matrixFile := flags.String("matrix", "", "path to the file describing the benchmark matrix which data constructors can be selected using -data (default: data constructors which don't require any parameters)")
+	dataConstructorName := flags.String("data", "data_similar_to_ssb_messages", "name of the data constructor used to generate values, if -matrix is set the name of a data constructor created from the matrix e.g. sized_data_fixed_1000_bytes_0_percent_compressible")
+	numberOfValues := flags.Int("n", 1000000, "number of values to generate")
+	obfuscate := flags.Bool("obfuscate", false, "scramble string values of JSON messages e.g. replayed using ssb_export, this is obfuscation and not anonymization")
+	seed := flags.Int64("seed", 0, "seed used to generate values (default: derived from the current time)")
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		return errors.Wrap(err, "error parsing flags")
+	}
+
+	if *output == "" {
+		return errors.New("output must be set")
+	}
+
+	if *numberOfValues <= 0 {
+		return errors.New("number of values must be positive")
+	}
+
+	matrix := dbbenchmark.Matrix{
+		Data: []dbbenchmark.MatrixData{
+			{
+				Name: *dataConstructorName,
+			},
+		},
+	}
+	if *matrixFile != "" {
+		loadedMatrix, err := dbbenchmark.LoadMatrix(*matrixFile)
+		if err != nil {
+			return errors.Wrap(err, "error loading the matrix")
+		}
+		matrix = loadedMatrix
+	}
+
+	dataConstructors, err := matrix.DataConstructors()
+	if err != nil {
+		return errors.Wrap(err, "error getting data constructors")
+	}
+	defer dbbenchmark.CloseDataConstructors(dataConstructors)
+
+	// without a matrix the only data constructor is named after its
+	// parameters e.g. sized_data becomes
+	// sized_data_fixed_1000_bytes_0_percent_compressible
+	dataConstructor := dataConstructors[0]
+	if *matrixFile != "" {
+		v, ok := findDataConstructor(dataConstructors, *dataConstructorName)
+		if !ok {
+			return fmt.Errorf("unknown data constructor '%s', the matrix contains %s", *dataConstructorName, dataConstructorNames(dataConstructors))
+		}
+		dataConstructor = v
+	}
+
+	runSeed := dbbenchmark.NewSeed()
+	if cmdutil.IsFlagSet(flags, "seed") {
+		runSeed = *seed
+	}
+
+	rnd := rand.New(rand.NewSource(runSeed))
+
+	var obfuscator *dbbenchmark.SSBObfuscator
+	if *obfuscate {
+		obfuscator = dbbenchmark.NewSSBObfuscator(rnd)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return errors.Wrap(err, "error creating the dataset file")
+	}
+	defer f.Close()
+
+	w := dataset.NewWriter(f)
+	for i := 0; i < *numberOfValues; i++ {
+		value := dataConstructor.Fn(rnd)
+		if obfuscator != nil {
+			value = obfuscator.Obfuscate(value)
+		}
+
+		if err := w.Write(value); err != nil {
+			return errors.Wrap(err, "error writing a value")
+		}
+	}
+
+	if dataConstructor.Err != nil {
+		if err := dataConstructor.Err(); err != nil {
+			return errors.Wrap(err, "data constructor returned an error")
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "error flushing the dataset")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "error closing the dataset file")
+	}
+
+	metadata := dataset.Metadata{
+		DataConstructor: dataConstructor.Name,
+		Seed:            runSeed,
+		Values:          *numberOfValues,
+		Obfuscated:      *obfuscate,
+	}
+
+	if err := dataset.WriteMetadata(*output, metadata); err != nil {
+		return errors.Wrap(err, "error writing the metadata")
+	}
+
+	fmt.Printf("wrote %d values generated by %s with seed %d to %s\n", *numberOfValues, dataConstructor.Name, runSeed, *output)
+	return nil
+}
+
+func dataConstructorNames(dataConstructors []dbbenchmark.DataConstructor) string {
+	var names []string
+	for _, dataConstructor := range dataConstructors {
+		names = append(names, fmt.Sprintf("'%s'", dataConstructor.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+func findDataConstructor(dataConstructors []dbbenchmark.DataConstructor, name string) (dbbenchmark.DataConstructor, bool) {
+	for _, dataConstructor := range dataConstructors {
+		if dataConstructor.Name == name {
+			return dataConstructor, true
+		}
+	}
+	return dbbenchmark.DataConstructor{}, false
+}
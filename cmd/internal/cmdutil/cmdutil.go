@@ -0,0 +1,16 @@
+// Package cmdutil contains helpers shared by the commands.
+package cmdutil
+
+import "flag"
+
+// IsFlagSet returns true if the flag with the given name was explicitly set
+// on the command line.
+func IsFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/boreq/db_benchmark/report"
 	"github.com/boreq/errors"
@@ -44,6 +46,9 @@ func run() error {
 	readmeBuffer.WriteString(fmt.Sprintf("goarch=%s\n", results.Goarch))
 	readmeBuffer.WriteString(fmt.Sprintf("goos=%s\n", results.Goos))
 	readmeBuffer.WriteString(fmt.Sprintf("cpu=%s\n", results.Cpu))
+	if results.Seed != "" {
+		readmeBuffer.WriteString(fmt.Sprintf("seed=%s\n", results.Seed))
+	}
 	readmeBuffer.WriteString("```\n")
 
 	readmeBuffer.WriteString("## Performance\n")
@@ -59,12 +64,7 @@ func run() error {
 			strings.Replace(result.BenchmarkName, string(os.PathSeparator), "-", -1),
 		)
 
-		f, err := os.Create(path.Join(directory, filename))
-		if err != nil {
-			return errors.Wrap(err, "error creating chart file")
-		}
-
-		if err := resultsChart.Render(gochart.PNG, f); err != nil {
+		if err := renderChart(path.Join(directory, filename), resultsChart); err != nil {
 			return errors.Wrap(err, "error rendering the chart")
 		}
 
@@ -79,6 +79,45 @@ func run() error {
 		}
 		readmeBuffer.WriteString("```\n")
 
+		for _, operation := range result.Operations() {
+			latencyChart, err := report.MakeLatencyResultChart(result, operation)
+			if err != nil {
+				return errors.Wrap(err, "error creating latency chart")
+			}
+
+			filename := fmt.Sprintf(
+				"%s-%s-latency.png",
+				strings.Replace(result.BenchmarkName, string(os.PathSeparator), "-", -1),
+				operation,
+			)
+
+			if err := renderChart(path.Join(directory, filename), latencyChart); err != nil {
+				return errors.Wrap(err, "error rendering the latency chart")
+			}
+
+			readmeBuffer.WriteString(fmt.Sprintf("#### %s latency\n", operation))
+			readmeBuffer.WriteString(fmt.Sprintf("![](./%s)\n", filename))
+			readmeBuffer.WriteString("```\n")
+			readmeBuffer.WriteString(fmt.Sprintf("%20s", ""))
+			for _, statistic := range report.LatencyStatistics {
+				readmeBuffer.WriteString(fmt.Sprintf(" %12s", statistic))
+			}
+			readmeBuffer.WriteString("\n")
+			for _, system := range result.Systems {
+				latencies, ok := system.Latencies[operation]
+				if !ok {
+					continue
+				}
+
+				readmeBuffer.WriteString(fmt.Sprintf("%20s", system.SystemName))
+				for _, statistic := range report.LatencyStatistics {
+					v, _ := latencies.Get(statistic)
+					readmeBuffer.WriteString(fmt.Sprintf(" %12.0f", v))
+				}
+				readmeBuffer.WriteString("\n")
+			}
+			readmeBuffer.WriteString("```\n")
+		}
 	}
 
 	readmeBuffer.WriteString("## Size\n")
@@ -97,12 +136,7 @@ func run() error {
 			strings.Replace(result.BenchmarkName, string(os.PathSeparator), "-", -1),
 		)
 
-		f, err := os.Create(path.Join(directory, filename))
-		if err != nil {
-			return errors.Wrap(err, "error creating chart file")
-		}
-
-		if err := resultsChart.Render(gochart.PNG, f); err != nil {
+		if err := renderChart(path.Join(directory, filename), resultsChart); err != nil {
 			return errors.Wrap(err, "error rendering the chart")
 		}
 
@@ -119,6 +153,69 @@ func run() error {
 
 	}
 
+	if len(results.SweepResults) > 0 {
+		readmeBuffer.WriteString("## Scalability\n")
+	}
+
+	for _, result := range results.SweepResults {
+		readmeBuffer.WriteString(fmt.Sprintf("### %s\n", result.BenchmarkName))
+
+		for _, system := range result.Systems {
+			sweepChart, err := report.MakeSweepResultChart(result, system)
+			if err != nil {
+				return errors.Wrap(err, "error creating sweep chart")
+			}
+
+			filename := fmt.Sprintf(
+				"%s-%s-sweep.png",
+				strings.Replace(result.BenchmarkName, string(os.PathSeparator), "-", -1),
+				system.SystemName,
+			)
+
+			if err := renderChart(path.Join(directory, filename), sweepChart); err != nil {
+				return errors.Wrap(err, "error rendering the sweep chart")
+			}
+
+			readmeBuffer.WriteString(fmt.Sprintf("#### %s\n", system.SystemName))
+			readmeBuffer.WriteString(fmt.Sprintf("![](./%s)\n", filename))
+			readmeBuffer.WriteString("```\n")
+			for _, point := range system.Points {
+				readmeBuffer.WriteString(fmt.Sprintf("%12d values %20s = %.0f ops per second\n", point.Size, point.Operation, float64(time.Second)/point.NsOp))
+			}
+			readmeBuffer.WriteString("```\n")
+		}
+	}
+
+	if len(results.TransactionSizeResults) > 0 {
+		readmeBuffer.WriteString("## Transaction size\n")
+	}
+
+	for _, result := range results.TransactionSizeResults {
+		transactionSizeChart, err := report.MakeTransactionSizeResultChart(result)
+		if err != nil {
+			return errors.Wrap(err, "error creating transaction size chart")
+		}
+
+		filename := fmt.Sprintf(
+			"%s-transaction-size.png",
+			strings.Replace(result.BenchmarkName, string(os.PathSeparator), "-", -1),
+		)
+
+		if err := renderChart(path.Join(directory, filename), transactionSizeChart); err != nil {
+			return errors.Wrap(err, "error rendering the transaction size chart")
+		}
+
+		readmeBuffer.WriteString(fmt.Sprintf("### %s\n", result.BenchmarkName))
+		readmeBuffer.WriteString(fmt.Sprintf("![](./%s)\n", filename))
+		readmeBuffer.WriteString("```\n")
+		for _, system := range result.Systems {
+			for _, point := range system.Points {
+				readmeBuffer.WriteString(fmt.Sprintf("%20s %8d = %.0f ns per op\n", system.SystemName, point.TransactionSize, point.NsOp))
+			}
+		}
+		readmeBuffer.WriteString("```\n")
+	}
+
 	readmeFile, err := os.Create(path.Join(directory, "README.md"))
 	if err != nil {
 		return errors.Wrap(err, "error creating readme")
@@ -130,3 +227,21 @@ func run() error {
 
 	return nil
 }
+
+type chartRenderer interface {
+	Render(rp gochart.RendererProvider, w io.Writer) error
+}
+
+func renderChart(filename string, c chartRenderer) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "error creating chart file")
+	}
+	defer f.Close()
+
+	if err := c.Render(gochart.PNG, f); err != nil {
+		return errors.Wrap(err, "error rendering")
+	}
+
+	return f.Close()
+}
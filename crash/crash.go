@@ -0,0 +1,372 @@
+// Package crash checks what survives when a process appending to a database
+// system is killed. A child process appends values and acknowledges every
+// committed transaction. The parent kills the child at a random point using
+// SIGKILL, reopens the database system and checks that it contains a
+// contiguous and uncorrupted prefix of the appended values which includes all
+// acknowledged values.
+package crash
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	dbbenchmark "github.com/boreq/db_benchmark"
+	"github.com/boreq/errors"
+)
+
+const (
+	DefaultRounds   = 20
+	DefaultMaxDelay = 500 * time.Millisecond
+)
+
+const (
+	minValueSize = 16
+	maxValueSize = 1024
+)
+
+// Config configures Run.
+type Config struct {
+	// Rounds is the number of times a child is started and killed.
+	Rounds int
+
+	// MaxDelay is the maximum time for which the child appends values
+	// before it is killed. The delay is chosen uniformly from
+	// [0, MaxDelay] and measured from the first acknowledgement.
+	MaxDelay time.Duration
+
+	// Seed determines the appended values and the delays.
+	Seed int64
+}
+
+// NewChildFunc returns a command which executes RunChild for the database
+// system in the given directory with the given seed. The standard output of
+// the command is used to receive acknowledgements.
+type NewChildFunc func(dir string, seed int64) *exec.Cmd
+
+// Result summarizes all rounds executed for a database system.
+type Result struct {
+	Rounds int
+
+	// AcknowledgedWrites is the number of values which were appended and
+	// acknowledged by the children before they were killed.
+	AcknowledgedWrites int
+
+	// LostAcknowledgedWrites is the number of acknowledged values which
+	// were missing after the database system was reopened.
+	LostAcknowledgedWrites int
+
+	// CorruptionDetected is the number of rounds after which the database
+	// system couldn't be reopened or didn't contain a contiguous prefix of
+	// the appended values.
+	CorruptionDetected int
+
+	// Corruptions describe the detected corruption.
+	Corruptions []string
+}
+
+// Run executes the configured number of rounds against the database system
+// stored in the given directory. Rounds continue appending to the values which
+// survived the previous round. If corruption is detected the directory is
+// cleared before the next round.
+func Run(system dbbenchmark.TestedDatabaseSystem, dir string, config Config, newChild NewChildFunc) (Result, error) {
+	if config.Rounds <= 0 {
+		return Result{}, errors.New("number of rounds must be positive")
+	}
+
+	if config.MaxDelay < 0 {
+		return Result{}, errors.New("max delay can't be negative")
+	}
+
+	rnd := rand.New(rand.NewSource(config.Seed))
+
+	var result Result
+	var surviving int
+
+	for i := 0; i < config.Rounds; i++ {
+		delay := time.Duration(rnd.Int63n(int64(config.MaxDelay) + 1))
+
+		acknowledged, err := runChild(newChild(dir, config.Seed), delay)
+		if err != nil {
+			return Result{}, errors.Wrapf(err, "error running the child in round %d", i)
+		}
+
+		result.Rounds++
+		if acknowledged > surviving {
+			result.AcknowledgedWrites += acknowledged - surviving
+		}
+
+		found, err := Verify(system, dir, config.Seed)
+		if err != nil {
+			result.CorruptionDetected++
+			result.Corruptions = append(result.Corruptions, fmt.Sprintf("round %d: %s", i, err))
+
+			if err := clearDirectory(dir); err != nil {
+				return Result{}, errors.Wrap(err, "error clearing the directory")
+			}
+			surviving = 0
+			continue
+		}
+
+		if found < acknowledged {
+			result.LostAcknowledgedWrites += acknowledged - found
+		}
+		surviving = found
+	}
+
+	return result, nil
+}
+
+// runChild starts the child, kills it after the delay measured from the first
+// acknowledgement and returns the number of values in the log according to
+// the last acknowledgement.
+func runChild(cmd *exec.Cmd, delay time.Duration) (int, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, errors.Wrap(err, "error creating the pipe")
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return 0, errors.Wrap(err, "error starting the child")
+	}
+
+	acknowledgements := make(chan int)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(acknowledgements)
+		readErr <- readAcknowledgements(stdout, acknowledgements)
+	}()
+
+	acknowledged := 0
+	var timer <-chan time.Time
+
+loop:
+	for {
+		select {
+		case v, ok := <-acknowledgements:
+			if !ok {
+				break loop
+			}
+
+			if timer == nil {
+				timer = time.After(delay)
+			}
+			acknowledged = v
+		case <-timer:
+			if err := cmd.Process.Kill(); err != nil {
+				return 0, errors.Wrap(err, "error killing the child")
+			}
+			timer = nil
+		}
+	}
+
+	if err := <-readErr; err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return 0, errors.Wrap(err, "error reading acknowledgements")
+	}
+
+	err = cmd.Wait()
+	if err == nil {
+		return 0, errors.New("child exited before it was killed")
+	}
+
+	if !wasKilled(err) {
+		return 0, errors.Wrapf(err, "child failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return acknowledged, nil
+}
+
+// readAcknowledgements sends acknowledgements until the child closes its
+// standard output which happens when it is killed.
+func readAcknowledgements(r io.Reader, acknowledgements chan<- int) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		v, err := strconv.Atoi(scanner.Text())
+		if err != nil {
+			return errors.Wrapf(err, "invalid acknowledgement '%s'", scanner.Text())
+		}
+		acknowledgements <- v
+	}
+	return scanner.Err()
+}
+
+func wasKilled(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// RunChild appends values to the database system until the process is
+// killed. Transactions of random sizes are used so that the process is
+// killed at different points of a transaction. After each transaction is
+// committed the number of values in the log is written to acknowledgements.
+func RunChild(system dbbenchmark.TestedDatabaseSystem, dir string, seed int64, acknowledgements io.Writer) error {
+	databaseSystem, err := system.DatabaseSystemConstructor(dir)
+	if err != nil {
+		return errors.Wrap(err, "error creating the database system")
+	}
+
+	return appendValues(databaseSystem, seed, acknowledgements)
+}
+
+// appendValues appends values following the last value in the log until an
+// error occurs.
+func appendValues(databaseSystem dbbenchmark.DatabaseSystem, seed int64, acknowledgements io.Writer) error {
+	var next dbbenchmark.Sequence
+	if err := databaseSystem.Read(func(reader dbbenchmark.Reader) error {
+		lastSequence, ok, err := reader.LastSequence()
+		if err != nil {
+			return errors.Wrap(err, "error calling last sequence")
+		}
+
+		if ok {
+			next = lastSequence + 1
+		}
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "error calling read")
+	}
+
+	rnd := rand.New(rand.NewSource(seed ^ int64(next)))
+
+	for {
+		n := 1 + rnd.Intn(databaseSystem.PreferredTransactionSize())
+
+		if err := databaseSystem.Update(func(updater dbbenchmark.Updater) error {
+			for i := 0; i < n; i++ {
+				seq, err := updater.Append(Value(seed, next+dbbenchmark.Sequence(i)))
+				if err != nil {
+					return errors.Wrap(err, "error calling append")
+				}
+
+				if seq != next+dbbenchmark.Sequence(i) {
+					return fmt.Errorf("expected sequence %d but got %d", next+dbbenchmark.Sequence(i), seq)
+				}
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error calling update")
+		}
+
+		next += dbbenchmark.Sequence(n)
+
+		if _, err := fmt.Fprintln(acknowledgements, next); err != nil {
+			return errors.Wrap(err, "error writing the acknowledgement")
+		}
+	}
+}
+
+// Verify reopens the database system and checks that it contains a
+// contiguous prefix of the values appended by RunChild. It returns the number
+// of values in the log. An error is returned if corruption is detected.
+func Verify(system dbbenchmark.TestedDatabaseSystem, dir string, seed int64) (int, error) {
+	databaseSystem, err := system.DatabaseSystemConstructor(dir)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reopening the database system")
+	}
+
+	var found int
+	if err := databaseSystem.Read(func(reader dbbenchmark.Reader) error {
+		lastSequence, ok, err := reader.LastSequence()
+		if err != nil {
+			return errors.Wrap(err, "error calling last sequence")
+		}
+
+		if !ok {
+			return nil
+		}
+
+		var expected dbbenchmark.Sequence
+		if err := reader.IterateRange(0, lastSequence+1, dbbenchmark.Forward, func(item dbbenchmark.Item) error {
+			if item.Sequence != expected {
+				return fmt.Errorf("value with sequence %d is missing", expected)
+			}
+
+			if !bytes.Equal(item.Value, Value(seed, item.Sequence)) {
+				return fmt.Errorf("value with sequence %d is corrupted", item.Sequence)
+			}
+
+			expected++
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error iterating")
+		}
+
+		if expected != lastSequence+1 {
+			return fmt.Errorf("values after sequence %d are missing, last sequence is %d", expected, lastSequence)
+		}
+
+		found = int(expected)
+		return nil
+	}); err != nil {
+		databaseSystem.Close()
+		return 0, errors.Wrap(err, "error calling read")
+	}
+
+	if err := databaseSystem.Close(); err != nil {
+		return 0, errors.Wrap(err, "error calling close")
+	}
+
+	return found, nil
+}
+
+// Value returns the value appended with the given sequence. Values start
+// with the sequence and their sizes vary.
+func Value(seed int64, seq dbbenchmark.Sequence) []byte {
+	state := uint64(seed) ^ uint64(seq)*0x9e3779b97f4a7c15
+
+	size := minValueSize + int(splitmix64(&state)%(maxValueSize-minValueSize+1))
+	value := make([]byte, size)
+	binary.BigEndian.PutUint64(value, uint64(seq))
+
+	for i := 8; i < len(value); i += 8 {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], splitmix64(&state))
+		copy(value[i:], b[:])
+	}
+
+	return value
+}
+
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func clearDirectory(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "error reading the directory")
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return errors.Wrap(err, "error removing the entry")
+		}
+	}
+
+	return nil
+}
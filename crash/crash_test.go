@@ -0,0 +1,164 @@
+package crash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	dbbenchmark "github.com/boreq/db_benchmark"
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	childSystemEnv = "CRASH_TEST_CHILD_SYSTEM"
+	childDirEnv    = "CRASH_TEST_CHILD_DIR"
+	childSeedEnv   = "CRASH_TEST_CHILD_SEED"
+
+	// childFileSizeMarginEnv is set by TestFaults.
+	childFileSizeMarginEnv = "CRASH_TEST_CHILD_FILE_SIZE_MARGIN"
+)
+
+// TestMain runs a child instead of the tests if the test binary was started
+// as a child by TestRun or TestFaults.
+func TestMain(m *testing.M) {
+	if name := os.Getenv(childSystemEnv); name != "" {
+		if err := runTestChild(name, os.Getenv(childDirEnv), os.Getenv(childSeedEnv), os.Getenv(childFileSizeMarginEnv)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func runTestChild(name, dir, seed, fileSizeMargin string) error {
+	s, err := strconv.ParseInt(seed, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	for _, system := range testSystems() {
+		if system.Name == name {
+			if fileSizeMargin != "" {
+				margin, err := strconv.ParseInt(fileSizeMargin, 10, 64)
+				if err != nil {
+					return err
+				}
+				return runFaultyChild(system, dir, s, margin)
+			}
+			return RunChild(system, dir, s, os.Stdout)
+		}
+	}
+
+	return fmt.Errorf("unknown system '%s'", name)
+}
+
+func TestRun(t *testing.T) {
+	for _, system := range testSystems() {
+		t.Run(system.Name, func(t *testing.T) {
+			dir := fixtures.Directory(t, "")
+
+			config := Config{
+				Rounds:   3,
+				MaxDelay: 50 * time.Millisecond,
+				Seed:     1,
+			}
+
+			result, err := Run(system, dir, config, func(dir string, seed int64) *exec.Cmd {
+				cmd := exec.Command(os.Args[0])
+				cmd.Env = append(os.Environ(),
+					childSystemEnv+"="+system.Name,
+					childDirEnv+"="+dir,
+					childSeedEnv+"="+strconv.FormatInt(seed, 10),
+				)
+				return cmd
+			})
+			require.NoError(t, err)
+			require.Equal(t, config.Rounds, result.Rounds)
+			require.Len(t, result.Corruptions, result.CorruptionDetected)
+			require.Positive(t, result.AcknowledgedWrites)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const seed = 1
+
+	for _, system := range testSystems() {
+		t.Run(system.Name, func(t *testing.T) {
+			dir := fixtures.Directory(t, "")
+
+			found, err := Verify(system, dir, seed)
+			require.NoError(t, err)
+			require.Equal(t, 0, found)
+
+			update(t, system, dir, func(updater dbbenchmark.Updater) error {
+				for i := 0; i < 10; i++ {
+					if _, err := updater.Append(Value(seed, dbbenchmark.Sequence(i))); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+			found, err = Verify(system, dir, seed)
+			require.NoError(t, err)
+			require.Equal(t, 10, found)
+
+			_, err = Verify(system, dir, seed+1)
+			require.ErrorContains(t, err, "value with sequence 0 is corrupted")
+
+			update(t, system, dir, func(updater dbbenchmark.Updater) error {
+				return updater.Delete(5)
+			})
+
+			_, err = Verify(system, dir, seed)
+			require.ErrorContains(t, err, "value with sequence 5 is missing")
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	sizes := make(map[int]bool)
+
+	for i := 0; i < 100; i++ {
+		seq := dbbenchmark.Sequence(i)
+
+		value := Value(1, seq)
+		require.Equal(t, value, Value(1, seq))
+		require.NotEqual(t, value, Value(2, seq))
+		require.Equal(t, uint64(seq), binary.BigEndian.Uint64(value))
+		require.True(t, len(value) >= minValueSize && len(value) <= maxValueSize)
+
+		sizes[len(value)] = true
+	}
+
+	require.Greater(t, len(sizes), 1)
+}
+
+func update(t *testing.T, system dbbenchmark.TestedDatabaseSystem, dir string, fn func(updater dbbenchmark.Updater) error) {
+	databaseSystem, err := system.DatabaseSystemConstructor(dir)
+	require.NoError(t, err)
+	require.NoError(t, databaseSystem.Update(fn))
+	require.NoError(t, databaseSystem.Close())
+}
+
+func testSystems() []dbbenchmark.TestedDatabaseSystem {
+	systems, err := dbbenchmark.Matrix{
+		Systems: []dbbenchmark.MatrixSystem{
+			{Type: dbbenchmark.BoltDatabaseSystemType},
+			{Type: dbbenchmark.BadgerDatabaseSystemType},
+			{Type: dbbenchmark.MargaretDatabaseSystemType},
+		},
+	}.DatabaseSystems()
+	if err != nil {
+		panic(err)
+	}
+	return systems
+}
@@ -0,0 +1,250 @@
+package crash
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	dbbenchmark "github.com/boreq/db_benchmark"
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/boreq/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFaults makes writes fail by limiting the size of files written by a
+// child appending to a database system. Depending on the limit writes fail
+// with EFBIG or are short. The database system must return an error from
+// Update, reopen without the limit, contain all acknowledged values and
+// accept new values.
+func TestFaults(t *testing.T) {
+	if !fileSizeLimitSupported {
+		t.Skip("limiting the file size isn't supported on this platform")
+	}
+
+	const (
+		seed          = 1
+		initialValues = 1000
+		timeout       = 30 * time.Second
+	)
+
+	// margins are added to the size of the largest file of the opened
+	// database system to choose the point at which writes start failing
+	margins := []int64{-1, 0, 1, 4 * 1024}
+
+	for _, system := range testSystems() {
+		for _, margin := range margins {
+			t.Run(fmt.Sprintf("%s/%d", system.Name, margin), func(t *testing.T) {
+				dir := fixtures.Directory(t, "")
+
+				update(t, system, dir, func(updater dbbenchmark.Updater) error {
+					for i := 0; i < initialValues; i++ {
+						if _, err := updater.Append(Value(seed, dbbenchmark.Sequence(i))); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+
+				cmd := exec.Command(os.Args[0])
+				cmd.Env = append(os.Environ(),
+					childSystemEnv+"="+system.Name,
+					childDirEnv+"="+dir,
+					childSeedEnv+"="+strconv.FormatInt(seed, 10),
+					childFileSizeMarginEnv+"="+strconv.FormatInt(margin, 10),
+				)
+
+				acknowledged, stderr, err := waitForFaultyChild(cmd, timeout)
+				require.NoError(t, err)
+				require.Contains(t, stderr, "error calling update", "update should fail")
+
+				knownFailure, isKnownFailure := knownReopenFailures[system.Config.Type]
+
+				var repair func() error
+				if isKnownFailure {
+					repair, err = knownFailure.PrepareRepair(dir)
+					require.NoError(t, err)
+				}
+
+				found, err := Verify(system, dir, seed)
+				if isKnownFailure {
+					if err == nil {
+						t.Logf("known issue didn't occur at this margin, %s", knownFailure.Description)
+					} else {
+						require.ErrorContains(t, err, knownFailure.Error, "reopening failed differently than described by the known issue")
+						t.Logf("known issue, %s: %s", knownFailure.Description, err)
+
+						require.NoError(t, repair(), "error repairing the database system")
+						found, err = Verify(system, dir, seed)
+					}
+				}
+				require.NoError(t, err, "the database system should reopen without corruption")
+				require.GreaterOrEqual(t, found, acknowledged, "acknowledged values were lost")
+				require.GreaterOrEqual(t, found, initialValues, "acknowledged values were lost")
+
+				update(t, system, dir, func(updater dbbenchmark.Updater) error {
+					_, err := updater.Append(Value(seed, dbbenchmark.Sequence(found)))
+					return err
+				})
+
+				foundAfterAppend, err := Verify(system, dir, seed)
+				require.NoError(t, err)
+				require.Equal(t, found+1, foundAfterAppend)
+			})
+		}
+	}
+}
+
+// knownReopenFailure describes a database system which can't be reopened
+// after writes failed.
+type knownReopenFailure struct {
+	Description string
+
+	// Error must be contained in the error returned when reopening fails.
+	// Depending on where writes start failing reopening may succeed.
+	Error string
+
+	// PrepareRepair is called before reopening the database system. It
+	// returns a function which fixes the directory after reopening failed
+	// so that the remaining checks can run.
+	PrepareRepair func(dir string) (func() error, error)
+}
+
+// knownReopenFailures are keyed by the type of the database system. Whether
+// the issue occurred is logged for every margin so that entries can be
+// removed once the issues are fixed.
+var knownReopenFailures = map[string]knownReopenFailure{
+	dbbenchmark.BadgerDatabaseSystemType: {
+		Description:   "badger leaves an empty memtable file behind if it fails to allocate it and then refuses to open it",
+		Error:         "while opening memtables",
+		PrepareRepair: prepareRemovingEmptyBadgerMemtables,
+	},
+}
+
+// prepareRemovingEmptyBadgerMemtables finds empty memtable files and
+// returns a function which removes them. The files have to be found before
+// badger is reopened as it writes a header to them before failing.
+func prepareRemovingEmptyBadgerMemtables(dir string) (func() error, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.mem"))
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing memtables")
+	}
+
+	var empty []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "error checking the memtable")
+		}
+
+		if info.Size() == 0 {
+			empty = append(empty, path)
+		}
+	}
+
+	return func() error {
+		if len(empty) == 0 {
+			return errors.New("there were no empty memtables")
+		}
+
+		for _, path := range empty {
+			if err := os.Remove(path); err != nil {
+				return errors.Wrap(err, "error removing the memtable")
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+// runFaultyChild opens the database system, limits the size of files to the
+// allocated size of its largest file plus the margin and appends values until an error
+// occurs.
+func runFaultyChild(system dbbenchmark.TestedDatabaseSystem, dir string, seed int64, margin int64) error {
+	databaseSystem, err := system.DatabaseSystemConstructor(dir)
+	if err != nil {
+		return errors.Wrap(err, "error creating the database system")
+	}
+
+	largest, err := largestFileSize(dir)
+	if err != nil {
+		return errors.Wrap(err, "error getting the size of the largest file")
+	}
+
+	if err := setFileSizeLimit(largest + margin); err != nil {
+		return errors.Wrap(err, "error limiting the file size")
+	}
+
+	return appendValues(databaseSystem, seed, os.Stdout)
+}
+
+// waitForFaultyChild waits until the child exits and returns the number of
+// values in the log according to the last acknowledgement and the standard
+// error of the child. The child must exit with an error before the timeout.
+func waitForFaultyChild(cmd *exec.Cmd, timeout time.Duration) (int, string, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, "", errors.Wrap(err, "error creating the pipe")
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return 0, "", errors.Wrap(err, "error starting the child")
+	}
+
+	timer := time.AfterFunc(timeout, func() {
+		cmd.Process.Kill()
+	})
+	defer timer.Stop()
+
+	acknowledgements := make(chan int)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(acknowledgements)
+		readErr <- readAcknowledgements(stdout, acknowledgements)
+	}()
+
+	acknowledged := 0
+	for v := range acknowledgements {
+		acknowledged = v
+	}
+
+	if err := <-readErr; err != nil {
+		return 0, "", errors.Wrap(err, "error reading acknowledgements")
+	}
+
+	err = cmd.Wait()
+	if err == nil {
+		return 0, "", errors.New("child exited without an error")
+	}
+
+	if wasKilled(err) {
+		return 0, "", fmt.Errorf("writes didn't fail within %s, acknowledged %d values", timeout, acknowledged)
+	}
+
+	return acknowledged, stderr.String(), nil
+}
+
+// largestFileSize returns the allocated size of the largest file as some
+// database systems preallocate sparse files which are much larger than the
+// data written to them.
+func largestFileSize(dir string) (int64, error) {
+	var largest int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if size := allocatedSize(info); !info.IsDir() && size > largest {
+			largest = size
+		}
+		return nil
+	})
+	return largest, err
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package crash
+
+import (
+	"os"
+
+	"github.com/boreq/errors"
+)
+
+const fileSizeLimitSupported = false
+
+func setFileSizeLimit(limit int64) error {
+	return errors.New("limiting the file size is only supported on unix")
+}
+
+func allocatedSize(info os.FileInfo) int64 {
+	return info.Size()
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package crash
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const fileSizeLimitSupported = true
+
+// setFileSizeLimit limits the size of files written by the process. Writes
+// which would exceed the limit fail with EFBIG or are short as the Go runtime
+// ignores SIGXFSZ.
+func setFileSizeLimit(limit int64) error {
+	return unix.Setrlimit(unix.RLIMIT_FSIZE, &unix.Rlimit{
+		Cur: uint64(limit),
+		Max: uint64(limit),
+	})
+}
+
+// allocatedSize returns the number of bytes allocated for the file which is
+// smaller than its size if the file is sparse.
+func allocatedSize(info os.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+	return stat.Blocks * 512
+}
@@ -0,0 +1,107 @@
+package db_benchmark
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/boreq/errors"
+)
+
+type DataConstructor struct {
+	Name string
+
+	// Fn returns a new value. All randomness must come from the provided
+	// source so that runs using the same seed generate the same values.
+	Fn func(rnd *rand.Rand) []byte
+
+	// Reset is optional. It is called before every run of a benchmark by
+	// data constructors which read values from a stream so that every run
+	// receives the same values.
+	Reset func() error
+
+	// Err is optional. It returns the first error encountered by Fn in
+	// data constructors which read values from a stream, once that happens
+	// Fn returns nil. It is checked after every run of a benchmark.
+	Err func() error
+
+	// Close is optional. It releases resources, such as files, held by data
+	// constructors which read values from a stream.
+	Close func() error
+}
+
+func (d DataConstructor) reset() error {
+	if d.Reset == nil {
+		return nil
+	}
+	return d.Reset()
+}
+
+func (d DataConstructor) err() error {
+	if d.Err == nil {
+		return nil
+	}
+	return d.Err()
+}
+
+// CloseDataConstructors closes all data constructors which hold resources.
+func CloseDataConstructors(dataConstructors []DataConstructor) error {
+	var closeErr error
+	for _, dataConstructor := range dataConstructors {
+		if dataConstructor.Close == nil {
+			continue
+		}
+
+		if err := dataConstructor.Close(); err != nil && closeErr == nil {
+			closeErr = errors.Wrapf(err, "error closing data constructor '%s'", dataConstructor.Name)
+		}
+	}
+	return closeErr
+}
+
+// DataConstructors returns all data constructors which can be used to
+// generate values inserted into the database systems.
+func DataConstructors() []DataConstructor {
+	return []DataConstructor{
+		RandomDataConstructor(),
+		SSBLikeDataConstructor(),
+	}
+}
+
+func RandomDataConstructor() DataConstructor {
+	return DataConstructor{
+		Name: "random_data",
+		Fn: func(rnd *rand.Rand) []byte {
+			return fixtures.RandomBytesFrom(rnd, 1000)
+		},
+	}
+}
+
+func SSBLikeDataConstructor() DataConstructor {
+	return DataConstructor{
+		Name: "data_similar_to_ssb_messages",
+		Fn: func(rnd *rand.Rand) []byte {
+			return []byte(
+				fmt.Sprintf(
+					`{
+	"previous": "%%%s.sha256",
+	"author": "@%s.ed25519",
+	"sequence": %d,
+	"timestamp": %d,
+	"hash": "sha256",
+	"content": {
+		"type": "post",
+		"text": "%s"
+	}
+}`,
+					base64.StdEncoding.EncodeToString(fixtures.RandomBytesFrom(rnd, 32)),
+					base64.StdEncoding.EncodeToString(fixtures.RandomBytesFrom(rnd, 32)),
+					rnd.Uint64()%10000,
+					rnd.Uint64(),
+					base64.StdEncoding.EncodeToString(fixtures.RandomBytesFrom(rnd, 100)),
+				),
+			)
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/boreq/db_benchmark/dataset"
+	"github.com/boreq/errors"
+)
+
+const DatasetData = "dataset"
+
+// DatasetDataConstructor returns a data constructor which streams values
+// from a dataset file created using cmd/dataset so that generating the values
+// isn't included in the measurements and all database systems receive the
+// same values. Once all values were read the dataset is read again from the
+// beginning. The data constructor is named after the file e.g. a file named
+// "ssb.dataset" results in "dataset_ssb".
+func DatasetDataConstructor(path string) (DataConstructor, error) {
+	stream, err := openFileStream(path, func(r io.Reader) valueReader {
+		return dataset.NewReader(r)
+	})
+	if err != nil {
+		return DataConstructor{}, errors.Wrap(err, "error opening the dataset")
+	}
+
+	return DataConstructor{
+		Name: fmt.Sprintf("%s_%s", DatasetData, fileBaseName(path)),
+		Fn: func(rnd *rand.Rand) []byte {
+			return stream.value()
+		},
+		Reset: stream.reset,
+		Err:   stream.err,
+		Close: stream.close,
+	}, nil
+}
+
+type valueReader interface {
+	// Next returns the next value or io.EOF if there are no more values.
+	Next() ([]byte, error)
+}
+
+// fileStream reads values from a file which remains open until the stream
+// is closed. Once all values were read the file is read again from the
+// beginning. It is safe for concurrent use.
+type fileStream struct {
+	mutex     sync.Mutex
+	file      *os.File
+	newReader func(r io.Reader) valueReader
+	reader    valueReader
+	readErr   error
+}
+
+// openFileStream opens the file and checks that it contains at least one
+// value.
+func openFileStream(path string, newReader func(r io.Reader) valueReader) (*fileStream, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening the file")
+	}
+
+	s := &fileStream{
+		file:      file,
+		newReader: newReader,
+		reader:    newReader(file),
+	}
+
+	if _, err := s.reader.Next(); err != nil {
+		file.Close()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file doesn't contain any values")
+		}
+		return nil, errors.Wrap(err, "error reading the first value")
+	}
+
+	if err := s.rewind(); err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "error rewinding the file")
+	}
+
+	return s, nil
+}
+
+// value returns the next value. If reading fails the error is recorded and
+// returned by err and from then on value returns nil.
+func (s *fileStream) value() []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.readErr != nil {
+		return nil
+	}
+
+	value, err := s.read()
+	if err != nil {
+		s.readErr = err
+		return nil
+	}
+
+	return value
+}
+
+func (s *fileStream) read() ([]byte, error) {
+	value, err := s.reader.Next()
+	if errors.Is(err, io.EOF) {
+		if err := s.rewind(); err != nil {
+			return nil, errors.Wrap(err, "error rewinding the file")
+		}
+		value, err = s.reader.Next()
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the next value")
+	}
+
+	return value, nil
+}
+
+func (s *fileStream) err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.readErr
+}
+
+func (s *fileStream) close() error {
+	return s.file.Close()
+}
+
+func (s *fileStream) reset() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.rewind()
+}
+
+func (s *fileStream) rewind() error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "seek failed")
+	}
+
+	s.reader = s.newReader(s.file)
+	return nil
+}
+
+// fileBaseName returns the name of the file without the directory and the
+// extension.
+func fileBaseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,88 @@
+package db_benchmark
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boreq/db_benchmark/dataset"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatasetDataConstructor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.dataset")
+
+	values := [][]byte{
+		[]byte("first"),
+		[]byte("second"),
+		[]byte("third"),
+	}
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	w := dataset.NewWriter(f)
+	for _, value := range values {
+		require.NoError(t, w.Write(value))
+	}
+	require.NoError(t, w.Flush())
+	require.NoError(t, f.Close())
+
+	dataConstructor, err := newDataConstructor(nil, MatrixData{Name: DatasetData, Path: path})
+	require.NoError(t, err)
+	require.Equal(t, "dataset_values", dataConstructor.Name)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2*len(values); i++ {
+		require.Equal(t, values[i%len(values)], dataConstructor.Fn(rnd), "values should be read again once exhausted")
+	}
+
+	require.Equal(t, values[0], dataConstructor.Fn(rnd))
+	require.NoError(t, dataConstructor.Reset())
+	require.Equal(t, values[0], dataConstructor.Fn(rnd), "reset should start from the beginning")
+	require.NoError(t, dataConstructor.Err())
+	require.NoError(t, dataConstructor.Close())
+}
+
+func TestDatasetDataConstructorRecordsReadErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "truncated.dataset")
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	w := dataset.NewWriter(f)
+	require.NoError(t, w.Write([]byte("first")))
+	require.NoError(t, w.Write([]byte("second")))
+	require.NoError(t, w.Flush())
+	require.NoError(t, f.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-1))
+
+	dataConstructor, err := DatasetDataConstructor(path)
+	require.NoError(t, err)
+	defer dataConstructor.Close()
+
+	rnd := rand.New(rand.NewSource(1))
+
+	require.Equal(t, []byte("first"), dataConstructor.Fn(rnd))
+	require.NoError(t, dataConstructor.Err())
+
+	require.Nil(t, dataConstructor.Fn(rnd))
+	require.ErrorIs(t, dataConstructor.Err(), io.ErrUnexpectedEOF)
+
+	require.NoError(t, dataConstructor.Reset())
+	require.Nil(t, dataConstructor.Fn(rnd), "errors should be permanent")
+}
+
+func TestDatasetDataConstructorRequiresPath(t *testing.T) {
+	_, err := newDataConstructor(nil, MatrixData{Name: DatasetData})
+	require.Error(t, err)
+
+	_, err = newDataConstructor(DataConstructors(), MatrixData{Name: "random_data", Path: "some/path"})
+	require.Error(t, err)
+}
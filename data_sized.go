@@ -0,0 +1,198 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/boreq/errors"
+)
+
+const SizedData = "sized_data"
+
+const (
+	SizeDistributionFixed     = "fixed"
+	SizeDistributionUniform   = "uniform"
+	SizeDistributionNormal    = "normal"
+	SizeDistributionLogNormal = "lognormal"
+	SizeDistributionBimodal   = "bimodal"
+)
+
+const (
+	DefaultSizeDistribution = SizeDistributionFixed
+	DefaultValueSize        = 1000
+
+	// maxValueSize limits the size of values drawn from unbounded
+	// distributions.
+	maxValueSize = 16 * 1024 * 1024
+)
+
+// compressibleText is repeated to fill the compressible parts of values.
+const compressibleText = "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. "
+
+// SizedDataConfig configures SizedDataConstructor.
+type SizedDataConfig struct {
+	// Distribution is one of SizeDistributionFixed,
+	// SizeDistributionUniform, SizeDistributionNormal,
+	// SizeDistributionLogNormal or SizeDistributionBimodal.
+	Distribution string
+
+	// Size is the size of all values for the fixed distribution, the mean
+	// for the normal distribution, the median for the log-normal
+	// distribution and the mean size of small values for the bimodal
+	// distribution.
+	Size int
+
+	// MinSize and MaxSize are the inclusive bounds of the uniform
+	// distribution.
+	MinSize int
+	MaxSize int
+
+	// StdDev is the standard deviation of the normal distribution and of
+	// both modes of the bimodal distribution.
+	StdDev int
+
+	// Sigma is the standard deviation of the natural logarithm of sizes
+	// drawn from the log-normal distribution.
+	Sigma float64
+
+	// LargeSize is the mean size of large values and LargePercentage is the
+	// percentage of large values drawn from the bimodal distribution.
+	LargeSize       int
+	LargePercentage int
+
+	// Compressibility is the percentage of each value which is filled with
+	// repeated text instead of random bytes.
+	Compressibility int
+}
+
+// SizedDataConstructor returns a data constructor which generates values
+// with sizes drawn from the configured distribution. Sizes are always at
+// least one byte. The name of the data constructor includes all settings
+// e.g. "sized_data_normal_1000_bytes_stddev_200_50_percent_compressible".
+func SizedDataConstructor(config SizedDataConfig) (DataConstructor, error) {
+	if config.Compressibility < 0 || config.Compressibility > 100 {
+		return DataConstructor{}, errors.New("compressibility must be in range [0, 100]")
+	}
+
+	var (
+		name string
+		size func(rnd *rand.Rand) int
+	)
+
+	switch config.Distribution {
+	case SizeDistributionFixed:
+		if config.Size <= 0 {
+			return DataConstructor{}, errors.New("size must be positive")
+		}
+
+		name = fmt.Sprintf("%s_%d_bytes", config.Distribution, config.Size)
+		size = func(rnd *rand.Rand) int {
+			return config.Size
+		}
+	case SizeDistributionUniform:
+		if config.MinSize <= 0 || config.MaxSize < config.MinSize {
+			return DataConstructor{}, errors.New("min size must be positive and not larger than max size")
+		}
+
+		name = fmt.Sprintf("%s_%d_to_%d_bytes", config.Distribution, config.MinSize, config.MaxSize)
+		size = func(rnd *rand.Rand) int {
+			return config.MinSize + rnd.Intn(config.MaxSize-config.MinSize+1)
+		}
+	case SizeDistributionNormal:
+		if config.Size <= 0 || config.StdDev <= 0 {
+			return DataConstructor{}, errors.New("size and standard deviation must be positive")
+		}
+
+		name = fmt.Sprintf("%s_%d_bytes_stddev_%d", config.Distribution, config.Size, config.StdDev)
+		size = func(rnd *rand.Rand) int {
+			return normalSize(rnd, config.Size, config.StdDev)
+		}
+	case SizeDistributionLogNormal:
+		if config.Size <= 0 || config.Sigma <= 0 {
+			return DataConstructor{}, errors.New("size and sigma must be positive")
+		}
+
+		name = fmt.Sprintf("%s_%d_bytes_sigma_%g", config.Distribution, config.Size, config.Sigma)
+		size = func(rnd *rand.Rand) int {
+			return clampSize(math.Exp(math.Log(float64(config.Size)) + config.Sigma*rnd.NormFloat64()))
+		}
+	case SizeDistributionBimodal:
+		if config.Size <= 0 || config.LargeSize <= config.Size {
+			return DataConstructor{}, errors.New("size must be positive and smaller than large size")
+		}
+
+		if config.LargePercentage <= 0 || config.LargePercentage >= 100 {
+			return DataConstructor{}, errors.New("large percentage must be in range (0, 100)")
+		}
+
+		if config.StdDev < 0 {
+			return DataConstructor{}, errors.New("standard deviation can't be negative")
+		}
+
+		name = fmt.Sprintf("%s_%d_and_%d_bytes_%d_percent_large", config.Distribution, config.Size, config.LargeSize, config.LargePercentage)
+		if config.StdDev > 0 {
+			name += fmt.Sprintf("_stddev_%d", config.StdDev)
+		}
+
+		size = func(rnd *rand.Rand) int {
+			mean := config.Size
+			if rnd.Intn(100) < config.LargePercentage {
+				mean = config.LargeSize
+			}
+			return normalSize(rnd, mean, config.StdDev)
+		}
+	default:
+		return DataConstructor{}, fmt.Errorf("unknown distribution '%s'", config.Distribution)
+	}
+
+	return DataConstructor{
+		Name: fmt.Sprintf("%s_%s_%d_percent_compressible", SizedData, name, config.Compressibility),
+		Fn: func(rnd *rand.Rand) []byte {
+			value := make([]byte, size(rnd))
+			fillValue(rnd, value, config.Compressibility)
+			return value
+		},
+	}, nil
+}
+
+func normalSize(rnd *rand.Rand, mean, stdDev int) int {
+	return clampSize(float64(mean) + float64(stdDev)*rnd.NormFloat64())
+}
+
+func clampSize(size float64) int {
+	if size < 1 {
+		return 1
+	}
+	if size > maxValueSize {
+		return maxValueSize
+	}
+	return int(math.Round(size))
+}
+
+// fillValue fills the value with chunks of random bytes and chunks of
+// repeated text spread evenly so that the given percentage of the value
+// consists of text.
+func fillValue(rnd *rand.Rand, value []byte, compressibility int) {
+	const chunkSize = 64
+
+	textOffset := rnd.Intn(len(compressibleText))
+	compressible := 0
+
+	for start := 0; start < len(value); start += chunkSize {
+		end := start + chunkSize
+		if end > len(value) {
+			end = len(value)
+		}
+
+		if (compressible+end-start)*100 <= end*compressibility {
+			for i := start; i < end; i++ {
+				value[i] = compressibleText[textOffset]
+				textOffset = (textOffset + 1) % len(compressibleText)
+			}
+			compressible += end - start
+		} else {
+			rnd.Read(value[start:end])
+		}
+	}
+}
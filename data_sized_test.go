@@ -0,0 +1,69 @@
+package db_benchmark
+
+import (
+	"bytes"
+	"compress/flate"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizedDataConstructorSizes(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	fixed, err := SizedDataConstructor(SizedDataConfig{Distribution: SizeDistributionFixed, Size: 123})
+	require.NoError(t, err)
+
+	uniform, err := SizedDataConstructor(SizedDataConfig{Distribution: SizeDistributionUniform, MinSize: 10, MaxSize: 20})
+	require.NoError(t, err)
+
+	bimodal, err := SizedDataConstructor(SizedDataConfig{Distribution: SizeDistributionBimodal, Size: 10, LargeSize: 1000, LargePercentage: 20})
+	require.NoError(t, err)
+
+	var large int
+	for i := 0; i < 1000; i++ {
+		require.Len(t, fixed.Fn(rnd), 123)
+
+		size := len(uniform.Fn(rnd))
+		require.GreaterOrEqual(t, size, 10)
+		require.LessOrEqual(t, size, 20)
+
+		switch len(bimodal.Fn(rnd)) {
+		case 10:
+		case 1000:
+			large++
+		default:
+			t.Fatal("unexpected size")
+		}
+	}
+
+	require.InDelta(t, 200, large, 50)
+}
+
+func TestSizedDataConstructorCompressibility(t *testing.T) {
+	compressedSize := func(compressibility int) int {
+		dataConstructor, err := SizedDataConstructor(SizedDataConfig{
+			Distribution:    SizeDistributionFixed,
+			Size:            10000,
+			Compressibility: compressibility,
+		})
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		w, err := flate.NewWriter(buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		_, err = w.Write(dataConstructor.Fn(rand.New(rand.NewSource(1))))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Len()
+	}
+
+	random := compressedSize(0)
+	half := compressedSize(50)
+	text := compressedSize(100)
+
+	require.Greater(t, random, 10000)
+	require.InDelta(t, 5000, half, 500)
+	require.Less(t, text, 1000)
+}
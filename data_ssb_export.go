@@ -0,0 +1,256 @@
+package db_benchmark
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"unicode/utf8"
+
+	"github.com/boreq/errors"
+)
+
+const SSBExportData = "ssb_export"
+
+// SSBExportDataConstructor returns a data constructor which replays messages
+// from a newline-delimited JSON export of an SSB log, for example the output
+// of "ssb-server createLogStream" or a go-ssb export. Every line is appended
+// as is so the mix of message types and their sizes is preserved. Once all
+// messages were read the export is read again from the beginning. The data
+// constructor is named after the file e.g. a file named "feeds.ndjson"
+// results in "ssb_export_feeds". To replay obfuscated messages create a
+// dataset using cmd/dataset, see SSBObfuscator.
+func SSBExportDataConstructor(path string) (DataConstructor, error) {
+	stream, err := openFileStream(path, func(r io.Reader) valueReader {
+		return newNDJSONReader(r)
+	})
+	if err != nil {
+		return DataConstructor{}, errors.Wrap(err, "error opening the export")
+	}
+
+	return DataConstructor{
+		Name: fmt.Sprintf("%s_%s", SSBExportData, fileBaseName(path)),
+		Fn: func(rnd *rand.Rand) []byte {
+			return stream.value()
+		},
+		Reset: stream.reset,
+		Err:   stream.err,
+		Close: stream.close,
+	}, nil
+}
+
+// ndjsonReader reads non-empty lines of newline-delimited JSON. Apart from
+// checking that each line starts with an object the lines aren't validated
+// to avoid parsing them while benchmarks are running.
+type ndjsonReader struct {
+	r *bufio.Reader
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	return &ndjsonReader{
+		r: bufio.NewReader(r),
+	}
+}
+
+func (r *ndjsonReader) Next() ([]byte, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, errors.Wrap(err, "error reading a line")
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			if line[0] != '{' {
+				return nil, errors.New("line doesn't contain a JSON object")
+			}
+			return line, nil
+		}
+
+		if err != nil {
+			return nil, io.EOF
+		}
+	}
+}
+
+// obfuscatedKeysToPreserve lists the keys which values aren't scrambled by
+// SSBObfuscator as they don't contain any private information and are needed
+// to tell message types apart.
+var obfuscatedKeysToPreserve = map[string]struct{}{
+	"type": {},
+	"hash": {},
+}
+
+// obfuscatedSuffixesToPreserve lists the suffixes of SSB identifiers which
+// aren't scrambled by SSBObfuscator.
+var obfuscatedSuffixesToPreserve = []string{
+	".sig.ed25519",
+	".ed25519",
+	".sha256",
+}
+
+// SSBObfuscator scrambles string values in JSON messages using a random
+// substitution of letters, digits and other characters which preserves the
+// length of the message, its structure and repetitions of values. Thanks to
+// that identifiers which appear in many messages still repeat and the
+// results of compression are close to the ones achieved for the original
+// messages. Object keys, values of obfuscatedKeysToPreserve, sigils and
+// suffixes of SSB identifiers are left intact. Escape sequences and values
+// other than strings, such as sequences and timestamps, aren't scrambled.
+//
+// This is obfuscation and not anonymization. A substitution which is fixed
+// for all messages can be reversed using frequency analysis and the
+// preserved lengths, structure, numbers and repetitions can be enough to
+// identify authors and messages. It only prevents casually reading the
+// contents of messages, exports of private data shouldn't be shared even
+// if they are obfuscated. The messages are scrambled once by cmd/dataset so
+// that obfuscating them isn't measured by the benchmarks.
+type SSBObfuscator struct {
+	lower  []byte
+	upper  []byte
+	digits []byte
+	key    uint64
+}
+
+// NewSSBObfuscator creates an obfuscator using a substitution selected using
+// the provided source of randomness.
+func NewSSBObfuscator(rnd *rand.Rand) *SSBObfuscator {
+	return &SSBObfuscator{
+		lower:  permutation(rnd, 'a', 26),
+		upper:  permutation(rnd, 'A', 26),
+		digits: permutation(rnd, '0', 10),
+		key:    rnd.Uint64(),
+	}
+}
+
+// Obfuscate returns a copy of the message with string values scrambled. If
+// the message isn't valid JSON the behaviour is undefined but the length of
+// the message is still preserved.
+func (o *SSBObfuscator) Obfuscate(message []byte) []byte {
+	result := make([]byte, len(message))
+	copy(result, message)
+
+	var lastKey []byte
+
+	for i := 0; i < len(message); i++ {
+		if message[i] != '"' {
+			continue
+		}
+
+		start := i + 1
+		end := findStringEnd(message, start)
+		if end < 0 {
+			break
+		}
+
+		j := end + 1
+		for j < len(message) && isJSONWhitespace(message[j]) {
+			j++
+		}
+
+		if j < len(message) && message[j] == ':' {
+			lastKey = message[start:end]
+		} else if _, ok := obfuscatedKeysToPreserve[string(lastKey)]; !ok {
+			o.obfuscateString(result[start:end])
+		}
+
+		i = end
+	}
+
+	return result
+}
+
+func (o *SSBObfuscator) obfuscateString(b []byte) {
+	from := 0
+	to := len(b)
+
+	if to > 0 && (b[0] == '@' || b[0] == '%' || b[0] == '&') {
+		from = 1
+	}
+
+	for _, suffix := range obfuscatedSuffixesToPreserve {
+		if bytes.HasSuffix(b[from:], []byte(suffix)) {
+			to -= len(suffix)
+			break
+		}
+	}
+
+	for i := from; i < to; {
+		c := b[i]
+		switch {
+		case c == '\\':
+			if i+1 < to && b[i+1] == 'u' {
+				i += 6
+			} else {
+				i += 2
+			}
+		case c >= 'a' && c <= 'z':
+			b[i] = o.lower[c-'a']
+			i++
+		case c >= 'A' && c <= 'Z':
+			b[i] = o.upper[c-'A']
+			i++
+		case c >= '0' && c <= '9':
+			b[i] = o.digits[c-'0']
+			i++
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRune(b[i:to])
+			if r != utf8.RuneError {
+				utf8.EncodeRune(b[i:i+size], o.substituteRune(r, size))
+			}
+			i += size
+		default:
+			i++
+		}
+	}
+}
+
+// substituteRune returns a rune which is encoded using the same number of
+// bytes as the provided rune.
+func (o *SSBObfuscator) substituteRune(r rune, size int) rune {
+	var from, to uint64
+	switch size {
+	case 2:
+		from, to = 0x100, 0x800
+	case 3:
+		// CJK Unified Ideographs which don't include surrogates
+		from, to = 0x4e00, 0xa000
+	default:
+		from, to = 0x10000, 0x110000
+	}
+
+	// splitmix64 finalizer
+	x := uint64(r) ^ o.key
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x = x ^ (x >> 31)
+
+	return rune(from + x%(to-from))
+}
+
+// findStringEnd returns the index of the quote which ends the JSON string
+// starting at the given index or -1 if the string isn't terminated.
+func findStringEnd(b []byte, start int) int {
+	for i := start; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func isJSONWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func permutation(rnd *rand.Rand, first byte, n int) []byte {
+	v := make([]byte, n)
+	for i, j := range rnd.Perm(n) {
+		v[i] = first + byte(j)
+	}
+	return v
+}
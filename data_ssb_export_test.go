@@ -0,0 +1,86 @@
+package db_benchmark
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSBExportDataConstructor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds.ndjson")
+
+	messages := []string{
+		`{"key":"%MnqTfdDTxNpVHMPF8x4lSkQ8adw5kFMaDOLAH9ugyNE=.sha256","value":{"author":"@FCX/tsDLpubCPKKfIrw4gc+SQkHcaD17s7GI6i/ziWY=.ed25519","sequence":1,"content":{"type":"post","text":"Hello world! Zażółć"}}}`,
+		`{"key":"%X6dI4rnq8b5v6SvyPbDVjp1SoKWCEFkHZoLgYMxLbd8=.sha256","value":{"author":"@FCX/tsDLpubCPKKfIrw4gc+SQkHcaD17s7GI6i/ziWY=.ed25519","sequence":2,"content":{"type":"contact","contact":"@Qfh5N5Wv7Cb0tt8gz+Ez8a85SqkhdDTm7Kkv1LXAAIE=.ed25519","following":true}}}`,
+		`{"key":"%Gz1RbZjUH6A5bsQQfGyZ3yqmqT3BK4Xz3pUbdcvz7XA=.sha256","value":{"author":"@FCX/tsDLpubCPKKfIrw4gc+SQkHcaD17s7GI6i/ziWY=.ed25519","sequence":3,"content":{"type":"vote","vote":{"link":"%MnqTfdDTxNpVHMPF8x4lSkQ8adw5kFMaDOLAH9ugyNE=.sha256","value":1,"expression":"Like \"quoted\""}}}}`,
+	}
+
+	err := os.WriteFile(path, []byte(strings.Join(messages, "\n")+"\n\n"), 0600)
+	require.NoError(t, err)
+
+	t.Run("replay", func(t *testing.T) {
+		dataConstructor, err := newDataConstructor(nil, MatrixData{Name: SSBExportData, Path: path})
+		require.NoError(t, err)
+		require.Equal(t, "ssb_export_feeds", dataConstructor.Name)
+
+		rnd := rand.New(rand.NewSource(1))
+
+		for i := 0; i < 2*len(messages); i++ {
+			require.Equal(t, messages[i%len(messages)], string(dataConstructor.Fn(rnd)))
+		}
+
+		require.NoError(t, dataConstructor.Reset())
+		require.Equal(t, messages[0], string(dataConstructor.Fn(rnd)))
+	})
+
+	t.Run("obfuscate", func(t *testing.T) {
+		read := func(seed int64) []map[string]any {
+			obfuscator := NewSSBObfuscator(rand.New(rand.NewSource(seed)))
+
+			var result []map[string]any
+			for _, message := range messages {
+				value := obfuscator.Obfuscate([]byte(message))
+				require.Len(t, value, len(message))
+				require.NotEqual(t, message, string(value))
+
+				var v map[string]any
+				require.NoError(t, json.Unmarshal(value, &v), string(value))
+				result = append(result, v)
+			}
+			return result
+		}
+
+		first := read(1)
+		require.Equal(t, first, read(1), "same seed should produce the same values")
+		require.NotEqual(t, first, read(2), "different seeds should produce different values")
+
+		value := func(v map[string]any, keys ...string) any {
+			var current any = v
+			for _, key := range keys {
+				current = current.(map[string]any)[key]
+			}
+			return current
+		}
+
+		require.Equal(t, "post", value(first[0], "value", "content", "type"))
+		require.Equal(t, "contact", value(first[1], "value", "content", "type"))
+		require.Equal(t, "vote", value(first[2], "value", "content", "type"))
+		require.Equal(t, true, value(first[1], "value", "content", "following"))
+		require.Equal(t, float64(2), value(first[1], "value", "sequence"))
+
+		author := value(first[0], "value", "author").(string)
+		require.True(t, strings.HasPrefix(author, "@"))
+		require.True(t, strings.HasSuffix(author, ".ed25519"))
+		require.Equal(t, author, value(first[1], "value", "author"), "repeated values should remain equal")
+		require.Equal(t, value(first[0], "key"), value(first[2], "value", "content", "vote", "link"))
+
+		text := value(first[0], "value", "content", "text").(string)
+		require.NotEqual(t, "Hello world! Zażółć", text)
+		require.Equal(t, len("Hello world! Zażółć"), len(text))
+	})
+}
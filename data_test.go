@@ -0,0 +1,24 @@
+package db_benchmark
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataConstructorsAreReproducible(t *testing.T) {
+	for _, dataConstructor := range DataConstructors() {
+		t.Run(dataConstructor.Name, func(t *testing.T) {
+			a := rand.New(rand.NewSource(1))
+			b := rand.New(rand.NewSource(1))
+			c := rand.New(rand.NewSource(2))
+
+			for i := 0; i < 10; i++ {
+				value := dataConstructor.Fn(a)
+				require.Equal(t, value, dataConstructor.Fn(b))
+				require.NotEqual(t, value, dataConstructor.Fn(c))
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package dataset reads and writes files containing a corpus of values which
+// can be replayed into the database systems. A file is a sequence of records,
+// each record consists of the length of the value encoded as a big-endian
+// uint32 followed by the value itself.
+package dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/boreq/errors"
+)
+
+const lengthSize = 4
+
+// Writer writes values to a dataset. Flush must be called once all values
+// were written.
+type Writer struct {
+	w *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: bufio.NewWriter(w),
+	}
+}
+
+// Write appends the value to the dataset.
+func (w *Writer) Write(value []byte) error {
+	if uint64(len(value)) > math.MaxUint32 {
+		return errors.New("value is too large")
+	}
+
+	var length [lengthSize]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(value)))
+
+	if _, err := w.w.Write(length[:]); err != nil {
+		return errors.Wrap(err, "error writing the length")
+	}
+
+	if _, err := w.w.Write(value); err != nil {
+		return errors.Wrap(err, "error writing the value")
+	}
+
+	return nil
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads values from a dataset.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r: bufio.NewReader(r),
+	}
+}
+
+// Next returns the next value from the dataset. It returns io.EOF if there
+// are no more values and io.ErrUnexpectedEOF if the dataset is truncated.
+func (r *Reader) Next() ([]byte, error) {
+	var length [lengthSize]byte
+
+	if _, err := io.ReadFull(r.r, length[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, errors.Wrap(err, "error reading the length")
+	}
+
+	value := make([]byte, binary.BigEndian.Uint32(length[:]))
+	if _, err := io.ReadFull(r.r, value); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, errors.Wrap(err, "error reading the value")
+	}
+
+	return value, nil
+}
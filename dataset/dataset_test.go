@@ -0,0 +1,58 @@
+package dataset
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	values := [][]byte{
+		[]byte("first"),
+		{},
+		bytes.Repeat([]byte("a"), 100000),
+		[]byte("last"),
+	}
+
+	buf := &bytes.Buffer{}
+
+	w := NewWriter(buf)
+	for _, value := range values {
+		require.NoError(t, w.Write(value))
+	}
+	require.NoError(t, w.Flush())
+
+	r := NewReader(buf)
+	for _, value := range values {
+		v, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, value, v)
+	}
+
+	_, err := r.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestTruncated(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	w := NewWriter(buf)
+	require.NoError(t, w.Write([]byte("value")))
+	require.NoError(t, w.Flush())
+
+	for i := 1; i < buf.Len(); i++ {
+		r := NewReader(bytes.NewReader(buf.Bytes()[:i]))
+
+		_, err := r.Next()
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	r := NewReader(&bytes.Buffer{})
+
+	_, err := r.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,61 @@
+package dataset
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/boreq/errors"
+)
+
+// Metadata describes how a dataset was generated. It is stored next to the
+// dataset in a file named using MetadataPath so that the dataset can be
+// generated again.
+type Metadata struct {
+	// DataConstructor is the name of the data constructor which generated
+	// the values.
+	DataConstructor string `json:"data_constructor"`
+
+	// Seed is the seed used to generate the values.
+	Seed int64 `json:"seed"`
+
+	// Values is the number of values in the dataset.
+	Values int `json:"values"`
+
+	// Obfuscated is set if string values of the messages were scrambled.
+	Obfuscated bool `json:"obfuscated,omitempty"`
+}
+
+// MetadataPath returns the path of the file containing the metadata of the
+// dataset stored at the given path.
+func MetadataPath(path string) string {
+	return path + ".json"
+}
+
+// WriteMetadata writes the metadata of the dataset stored at the given path.
+func WriteMetadata(path string, metadata Metadata) error {
+	b, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling the metadata")
+	}
+
+	if err := os.WriteFile(MetadataPath(path), append(b, '\n'), 0644); err != nil {
+		return errors.Wrap(err, "error writing the file")
+	}
+
+	return nil
+}
+
+// ReadMetadata reads the metadata of the dataset stored at the given path.
+func ReadMetadata(path string) (Metadata, error) {
+	b, err := os.ReadFile(MetadataPath(path))
+	if err != nil {
+		return Metadata{}, errors.Wrap(err, "error reading the file")
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return Metadata{}, errors.Wrap(err, "error unmarshaling the metadata")
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,31 @@
+package dataset
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ssb.dataset")
+
+	metadata := Metadata{
+		DataConstructor: "data_similar_to_ssb_messages",
+		Seed:            42,
+		Values:          1000,
+		Obfuscated:      true,
+	}
+
+	require.NoError(t, WriteMetadata(path, metadata))
+	require.FileExists(t, filepath.Join(filepath.Dir(path), "ssb.dataset.json"))
+
+	readMetadata, err := ReadMetadata(path)
+	require.NoError(t, err)
+	require.Equal(t, metadata, readMetadata)
+}
+
+func TestMetadataMissing(t *testing.T) {
+	_, err := ReadMetadata(filepath.Join(t.TempDir(), "ssb.dataset"))
+	require.Error(t, err)
+}
@@ -0,0 +1,1043 @@
+// Package dbtest implements a conformance test suite which every
+// implementation of db_benchmark.DatabaseSystem must pass.
+package dbtest
+
+import (
+	"fmt"
+	"testing"
+
+	dbbenchmark "github.com/boreq/db_benchmark"
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/boreq/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDatabaseSystem runs all conformance tests against database systems
+// created using the provided constructor. Each test uses a new directory.
+func TestDatabaseSystem(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	t.Run("append_and_get", func(t *testing.T) {
+		testAppendAndGet(t, constructor)
+	})
+
+	t.Run("sequences_start_at_zero", func(t *testing.T) {
+		testSequencesStartAtZero(t, constructor)
+	})
+
+	t.Run("sequences_continue_across_transactions", func(t *testing.T) {
+		testSequencesContinueAcrossTransactions(t, constructor)
+	})
+
+	t.Run("large_transaction", func(t *testing.T) {
+		testLargeTransaction(t, constructor)
+	})
+
+	t.Run("append_returns_sequences", func(t *testing.T) {
+		testAppendReturnsSequences(t, constructor)
+	})
+
+	t.Run("last_sequence", func(t *testing.T) {
+		testLastSequence(t, constructor)
+	})
+
+	t.Run("iterate", func(t *testing.T) {
+		testIterate(t, constructor)
+	})
+
+	t.Run("iterate_range", func(t *testing.T) {
+		testIterateRange(t, constructor)
+	})
+
+	t.Run("iterate_in_numeric_order", func(t *testing.T) {
+		testIterateInNumericOrder(t, constructor)
+	})
+
+	t.Run("iterate_empty", func(t *testing.T) {
+		testIterateEmpty(t, constructor)
+	})
+
+	t.Run("get_missing", func(t *testing.T) {
+		testGetMissing(t, constructor)
+	})
+
+	t.Run("persistence", func(t *testing.T) {
+		testPersistence(t, constructor)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		testDelete(t, constructor)
+	})
+
+	t.Run("delete_range", func(t *testing.T) {
+		testDeleteRange(t, constructor)
+	})
+
+	t.Run("delete_missing", func(t *testing.T) {
+		testDeleteMissing(t, constructor)
+	})
+
+	t.Run("delete_persistence", func(t *testing.T) {
+		testDeletePersistence(t, constructor)
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		testCompact(t, constructor)
+	})
+
+	t.Run("feeds", func(t *testing.T) {
+		testFeeds(t, constructor)
+	})
+
+	t.Run("key_index", func(t *testing.T) {
+		testKeyIndex(t, constructor)
+	})
+}
+
+// testFeeds runs the tests of FeedDatabaseSystem if it is implemented by
+// the database system.
+func testFeeds(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	t.Run("append_and_iterate", func(t *testing.T) {
+		testFeedsAppendAndIterate(t, constructor)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		testFeedsEmpty(t, constructor)
+	})
+
+	t.Run("separate_from_log", func(t *testing.T) {
+		testFeedsSeparateFromLog(t, constructor)
+	})
+
+	t.Run("persistence", func(t *testing.T) {
+		testFeedsPersistence(t, constructor)
+	})
+
+	t.Run("delete_feed", func(t *testing.T) {
+		testFeedsDeleteFeed(t, constructor)
+	})
+}
+
+// testKeyIndex runs the tests of KeyIndexDatabaseSystem if it is
+// implemented by the database system.
+func testKeyIndex(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	t.Run("append_and_get", func(t *testing.T) {
+		testKeyIndexAppendAndGet(t, constructor)
+	})
+
+	t.Run("get_missing", func(t *testing.T) {
+		testKeyIndexGetMissing(t, constructor)
+	})
+
+	t.Run("persistence", func(t *testing.T) {
+		testKeyIndexPersistence(t, constructor)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		testKeyIndexDelete(t, constructor)
+	})
+}
+
+func testAppendAndGet(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	values := appendValues(t, system, 10)
+
+	err := system.Read(func(reader dbbenchmark.Reader) error {
+		for i, expectedValue := range values {
+			value, err := reader.Get(dbbenchmark.Sequence(i))
+			if err != nil {
+				return errors.Wrap(err, "error calling get")
+			}
+			require.Equal(t, expectedValue, value)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testSequencesStartAtZero(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	values := appendValues(t, system, 1)
+
+	err := system.Read(func(reader dbbenchmark.Reader) error {
+		value, err := reader.Get(0)
+		if err != nil {
+			return errors.Wrap(err, "error calling get")
+		}
+		require.Equal(t, values[0], value)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testSequencesContinueAcrossTransactions(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	var values [][]byte
+	for i := 0; i < 3; i++ {
+		values = append(values, appendValues(t, system, 5)...)
+	}
+
+	require.Equal(t, values, iterateValues(t, system, 0, len(values)+10))
+}
+
+// testLargeTransaction appends more data in a single update than badger
+// allows in a single transaction.
+func testLargeTransaction(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	const (
+		numberOfValues = 200
+		valueSize      = 100 * 1024
+	)
+
+	var values [][]byte
+	err := system.Update(func(updater dbbenchmark.Updater) error {
+		for i := 0; i < numberOfValues; i++ {
+			value := fixtures.RandomBytes(valueSize)
+			seq, err := updater.Append(value)
+			if err != nil {
+				return errors.Wrap(err, "error calling append")
+			}
+			require.Equal(t, dbbenchmark.Sequence(i), seq)
+			values = append(values, value)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	requireLastSequence(t, system, numberOfValues-1, true)
+	require.Equal(t, values, iterateValues(t, system, 0, numberOfValues+10))
+}
+
+func testAppendReturnsSequences(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	var sequences []dbbenchmark.Sequence
+	var expectedSequences []dbbenchmark.Sequence
+
+	for i := 0; i < 3; i++ {
+		err := system.Update(func(updater dbbenchmark.Updater) error {
+			for j := 0; j < 5; j++ {
+				seq, err := updater.Append(fixtures.RandomBytes(100))
+				if err != nil {
+					return errors.Wrap(err, "error calling append")
+				}
+				sequences = append(sequences, seq)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	for i := range sequences {
+		expectedSequences = append(expectedSequences, dbbenchmark.Sequence(i))
+	}
+
+	require.Equal(t, expectedSequences, sequences)
+}
+
+func testLastSequence(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	dir := fixtures.Directory(t, "")
+
+	system, err := constructor(dir)
+	require.NoError(t, err)
+
+	requireLastSequence(t, system, 0, false)
+
+	appendValues(t, system, 1)
+	requireLastSequence(t, system, 0, true)
+
+	appendValues(t, system, 10)
+	requireLastSequence(t, system, 10, true)
+
+	require.NoError(t, system.Sync())
+	require.NoError(t, system.Close())
+
+	system = newDatabaseSystem(t, dir, constructor)
+	requireLastSequence(t, system, 10, true)
+}
+
+func testIterate(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	values := appendValues(t, system, 20)
+
+	testCases := []struct {
+		Start          dbbenchmark.Sequence
+		Limit          int
+		ExpectedValues [][]byte
+	}{
+		{
+			Start:          0,
+			Limit:          len(values),
+			ExpectedValues: values,
+		},
+		{
+			Start:          0,
+			Limit:          0,
+			ExpectedValues: nil,
+		},
+		{
+			Start:          0,
+			Limit:          1,
+			ExpectedValues: values[:1],
+		},
+		{
+			Start:          5,
+			Limit:          10,
+			ExpectedValues: values[5:15],
+		},
+		{
+			Start:          15,
+			Limit:          10,
+			ExpectedValues: values[15:],
+		},
+		{
+			Start:          19,
+			Limit:          10,
+			ExpectedValues: values[19:],
+		},
+		{
+			Start:          20,
+			Limit:          10,
+			ExpectedValues: nil,
+		},
+		{
+			Start:          100,
+			Limit:          10,
+			ExpectedValues: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(fmt.Sprintf("start_%d_limit_%d", testCase.Start, testCase.Limit), func(t *testing.T) {
+			var sequences []dbbenchmark.Sequence
+			var expectedSequences []dbbenchmark.Sequence
+
+			err := system.Read(func(reader dbbenchmark.Reader) error {
+				return reader.Iterate(testCase.Start, testCase.Limit, func(item dbbenchmark.Item) error {
+					sequences = append(sequences, item.Sequence)
+					return nil
+				})
+			})
+			require.NoError(t, err)
+
+			for i := range testCase.ExpectedValues {
+				expectedSequences = append(expectedSequences, testCase.Start+dbbenchmark.Sequence(i))
+			}
+
+			require.Equal(t, expectedSequences, sequences)
+			require.Equal(t, testCase.ExpectedValues, iterateValues(t, system, testCase.Start, testCase.Limit))
+		})
+	}
+}
+
+func testIterateRange(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	values := appendValues(t, system, 300)
+
+	testCases := []struct {
+		Start          dbbenchmark.Sequence
+		End            dbbenchmark.Sequence
+		ExpectedValues [][]byte
+	}{
+		{
+			Start:          0,
+			End:            dbbenchmark.Sequence(len(values)),
+			ExpectedValues: values,
+		},
+		{
+			Start:          0,
+			End:            1,
+			ExpectedValues: values[:1],
+		},
+		{
+			Start:          250,
+			End:            270,
+			ExpectedValues: values[250:270],
+		},
+		{
+			Start:          290,
+			End:            1000,
+			ExpectedValues: values[290:],
+		},
+		{
+			Start:          299,
+			End:            300,
+			ExpectedValues: values[299:],
+		},
+		{
+			Start:          300,
+			End:            310,
+			ExpectedValues: nil,
+		},
+		{
+			Start:          10,
+			End:            10,
+			ExpectedValues: nil,
+		},
+		{
+			Start:          20,
+			End:            10,
+			ExpectedValues: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(fmt.Sprintf("start_%d_end_%d", testCase.Start, testCase.End), func(t *testing.T) {
+			t.Run("forward", func(t *testing.T) {
+				var expectedSequences []dbbenchmark.Sequence
+				for i := range testCase.ExpectedValues {
+					expectedSequences = append(expectedSequences, testCase.Start+dbbenchmark.Sequence(i))
+				}
+
+				sequences, values := iterateRange(t, system, testCase.Start, testCase.End, dbbenchmark.Forward)
+				require.Equal(t, expectedSequences, sequences)
+				require.Equal(t, testCase.ExpectedValues, values)
+			})
+
+			t.Run("backward", func(t *testing.T) {
+				var expectedSequences []dbbenchmark.Sequence
+				var expectedValues [][]byte
+				for i := len(testCase.ExpectedValues) - 1; i >= 0; i-- {
+					expectedSequences = append(expectedSequences, testCase.Start+dbbenchmark.Sequence(i))
+					expectedValues = append(expectedValues, testCase.ExpectedValues[i])
+				}
+
+				sequences, values := iterateRange(t, system, testCase.Start, testCase.End, dbbenchmark.Backward)
+				require.Equal(t, expectedSequences, sequences)
+				require.Equal(t, expectedValues, values)
+			})
+		})
+	}
+}
+
+// testIterateInNumericOrder makes sure that sequences aren't ordered
+// lexicographically by their little-endian representation.
+func testIterateInNumericOrder(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	values := appendValues(t, system, 300)
+
+	require.Equal(t, values, iterateValues(t, system, 0, len(values)))
+	require.Equal(t, values[250:270], iterateValues(t, system, 250, 20))
+}
+
+func testIterateEmpty(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	require.Empty(t, iterateValues(t, system, 0, 10))
+
+	for _, direction := range []dbbenchmark.Direction{dbbenchmark.Forward, dbbenchmark.Backward} {
+		sequences, values := iterateRange(t, system, 0, 10, direction)
+		require.Empty(t, sequences)
+		require.Empty(t, values)
+	}
+}
+
+func testGetMissing(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	err := system.Read(func(reader dbbenchmark.Reader) error {
+		_, err := reader.Get(0)
+		return err
+	})
+	require.ErrorIs(t, err, dbbenchmark.ErrNotFound, "get on an empty database should fail")
+
+	appendValues(t, system, 5)
+
+	err = system.Read(func(reader dbbenchmark.Reader) error {
+		_, err := reader.Get(5)
+		return err
+	})
+	require.ErrorIs(t, err, dbbenchmark.ErrNotFound, "get after the last sequence should fail")
+}
+
+func testPersistence(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	dir := fixtures.Directory(t, "")
+
+	system, err := constructor(dir)
+	require.NoError(t, err)
+
+	values := appendValues(t, system, 10)
+
+	require.NoError(t, system.Sync())
+	require.NoError(t, system.Close())
+
+	system = newDatabaseSystem(t, dir, constructor)
+
+	require.Equal(t, values, iterateValues(t, system, 0, len(values)))
+
+	values = append(values, appendValues(t, system, 10)...)
+
+	require.Equal(t, values, iterateValues(t, system, 0, len(values)))
+}
+
+func testDelete(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	values := appendValues(t, system, 10)
+
+	err := system.Update(func(updater dbbenchmark.Updater) error {
+		for _, seq := range []dbbenchmark.Sequence{0, 4, 9} {
+			if err := updater.Delete(seq); err != nil {
+				return errors.Wrap(err, "error calling delete")
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, seq := range []dbbenchmark.Sequence{0, 4, 9} {
+		err := system.Read(func(reader dbbenchmark.Reader) error {
+			_, err := reader.Get(seq)
+			return err
+		})
+		require.ErrorIs(t, err, dbbenchmark.ErrNotFound, "get should fail after delete")
+	}
+
+	expectedValues := [][]byte{values[1], values[2], values[3], values[5], values[6], values[7], values[8]}
+	require.Equal(t, expectedValues, iterateValues(t, system, 0, 100))
+	require.Equal(t, expectedValues[:2], iterateValues(t, system, 0, 2), "deleted values shouldn't count towards the limit")
+
+	sequences, _ := iterateRange(t, system, 0, 10, dbbenchmark.Backward)
+	require.Equal(t, []dbbenchmark.Sequence{8, 7, 6, 5, 3, 2, 1}, sequences)
+
+	requireLastSequence(t, system, 9, true)
+
+	err = system.Update(func(updater dbbenchmark.Updater) error {
+		seq, err := updater.Append(fixtures.RandomBytes(10))
+		if err != nil {
+			return errors.Wrap(err, "error calling append")
+		}
+		require.Equal(t, dbbenchmark.Sequence(10), seq, "sequences of deleted values shouldn't be reused")
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testDeleteRange(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	values := appendValues(t, system, 10)
+
+	err := system.Update(func(updater dbbenchmark.Updater) error {
+		return updater.DeleteRange(2, 8)
+	})
+	require.NoError(t, err)
+
+	expectedValues := [][]byte{values[0], values[1], values[8], values[9]}
+	require.Equal(t, expectedValues, iterateValues(t, system, 0, 100))
+
+	err = system.Update(func(updater dbbenchmark.Updater) error {
+		return updater.DeleteRange(8, 100)
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, expectedValues[:2], iterateValues(t, system, 0, 100))
+	requireLastSequence(t, system, 9, true)
+}
+
+func testDeleteMissing(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	err := system.Update(func(updater dbbenchmark.Updater) error {
+		if err := updater.Delete(0); err != nil {
+			return errors.Wrap(err, "error deleting in an empty database")
+		}
+		return updater.DeleteRange(0, 10)
+	})
+	require.NoError(t, err)
+
+	values := appendValues(t, system, 5)
+
+	err = system.Update(func(updater dbbenchmark.Updater) error {
+		if err := updater.Delete(10); err != nil {
+			return errors.Wrap(err, "error deleting after the last sequence")
+		}
+		if err := updater.Delete(2); err != nil {
+			return errors.Wrap(err, "error calling delete")
+		}
+		if err := updater.Delete(2); err != nil {
+			return errors.Wrap(err, "error deleting twice")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, [][]byte{values[0], values[1], values[3], values[4]}, iterateValues(t, system, 0, 100))
+}
+
+func testDeletePersistence(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	dir := fixtures.Directory(t, "")
+
+	system, err := constructor(dir)
+	require.NoError(t, err)
+
+	values := appendValues(t, system, 10)
+
+	err = system.Update(func(updater dbbenchmark.Updater) error {
+		return updater.DeleteRange(0, 5)
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, system.Sync())
+	require.NoError(t, system.Close())
+
+	system = newDatabaseSystem(t, dir, constructor)
+
+	require.Equal(t, values[5:], iterateValues(t, system, 0, 100))
+	requireLastSequence(t, system, 9, true)
+}
+
+func testCompact(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	dir := fixtures.Directory(t, "")
+
+	system, err := constructor(dir)
+	require.NoError(t, err)
+
+	compactor, ok := system.(dbbenchmark.Compactor)
+	if !ok {
+		require.NoError(t, system.Close())
+		t.Skip("compaction is not supported")
+	}
+
+	values := appendValues(t, system, 100)
+
+	err = system.Update(func(updater dbbenchmark.Updater) error {
+		return updater.DeleteRange(0, 50)
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, compactor.Compact())
+
+	require.Equal(t, values[50:], iterateValues(t, system, 0, 100))
+	requireLastSequence(t, system, 99, true)
+
+	values = append(values, appendValues(t, system, 10)...)
+	require.Equal(t, values[50:], iterateValues(t, system, 0, 100))
+
+	require.NoError(t, system.Sync())
+	require.NoError(t, system.Close())
+
+	system = newDatabaseSystem(t, dir, constructor)
+	require.Equal(t, values[50:], iterateValues(t, system, 0, 100))
+}
+
+func requireLastSequence(t *testing.T, system dbbenchmark.DatabaseSystem, expectedSequence dbbenchmark.Sequence, expectedOk bool) {
+	err := system.Read(func(reader dbbenchmark.Reader) error {
+		seq, ok, err := reader.LastSequence()
+		if err != nil {
+			return errors.Wrap(err, "error calling last sequence")
+		}
+		require.Equal(t, expectedOk, ok)
+		require.Equal(t, expectedSequence, seq)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func newDatabaseSystem(t *testing.T, dir string, constructor dbbenchmark.DatabaseSystemConstructor) dbbenchmark.DatabaseSystem {
+	system, err := constructor(dir)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, system.Close())
+	})
+
+	return system
+}
+
+func appendValues(t *testing.T, system dbbenchmark.DatabaseSystem, n int) [][]byte {
+	var values [][]byte
+
+	err := system.Update(func(updater dbbenchmark.Updater) error {
+		for i := 0; i < n; i++ {
+			value := fixtures.RandomBytes(100)
+			if _, err := updater.Append(value); err != nil {
+				return errors.Wrap(err, "error calling append")
+			}
+			values = append(values, value)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	return values
+}
+
+func iterateValues(t *testing.T, system dbbenchmark.DatabaseSystem, start dbbenchmark.Sequence, limit int) [][]byte {
+	var values [][]byte
+
+	err := system.Read(func(reader dbbenchmark.Reader) error {
+		return reader.Iterate(start, limit, func(item dbbenchmark.Item) error {
+			values = append(values, append([]byte(nil), item.Value...))
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	return values
+}
+
+func iterateRange(t *testing.T, system dbbenchmark.DatabaseSystem, start, end dbbenchmark.Sequence, direction dbbenchmark.Direction) ([]dbbenchmark.Sequence, [][]byte) {
+	var sequences []dbbenchmark.Sequence
+	var values [][]byte
+
+	err := system.Read(func(reader dbbenchmark.Reader) error {
+		return reader.IterateRange(start, end, direction, func(item dbbenchmark.Item) error {
+			sequences = append(sequences, item.Sequence)
+			values = append(values, append([]byte(nil), item.Value...))
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	return sequences, values
+}
+
+func testFeedsAppendAndIterate(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newFeedDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	authors := newAuthors(3)
+	values := appendToFeeds(t, system, authors, 300)
+
+	for _, author := range authors {
+		feedValues := values[author]
+
+		require.Equal(t, feedValues, iterateFeed(t, system, author, 0, len(feedValues)+10))
+		require.Equal(t, feedValues[5:15], iterateFeed(t, system, author, 5, 10))
+		require.Equal(t, feedValues[90:], iterateFeed(t, system, author, 90, 10))
+		require.Empty(t, iterateFeed(t, system, author, dbbenchmark.Sequence(len(feedValues)), 10))
+
+		var sequences []dbbenchmark.Sequence
+		var expectedSequences []dbbenchmark.Sequence
+
+		err := system.ReadFeeds(func(reader dbbenchmark.FeedReader) error {
+			return reader.IterateFeed(author, 20, 5, func(item dbbenchmark.Item) error {
+				sequences = append(sequences, item.Sequence)
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		for i := 20; i < 25; i++ {
+			expectedSequences = append(expectedSequences, dbbenchmark.Sequence(i))
+		}
+
+		require.Equal(t, expectedSequences, sequences)
+		requireLastFeedSequence(t, system, author, dbbenchmark.Sequence(len(feedValues)-1), true)
+	}
+}
+
+func testFeedsEmpty(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newFeedDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	authors := newAuthors(2)
+
+	requireLastFeedSequence(t, system, authors[0], 0, false)
+	require.Empty(t, iterateFeed(t, system, authors[0], 0, 10))
+
+	appendToFeeds(t, system, authors[1:], 10)
+
+	requireLastFeedSequence(t, system, authors[0], 0, false)
+	require.Empty(t, iterateFeed(t, system, authors[0], 0, 10))
+}
+
+func testFeedsSeparateFromLog(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newFeedDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	appendToFeeds(t, system, newAuthors(2), 10)
+	requireLastSequence(t, system, 0, false)
+
+	values := appendValues(t, system, 10)
+	require.Equal(t, values, iterateValues(t, system, 0, 100))
+}
+
+func testFeedsPersistence(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	dir := fixtures.Directory(t, "")
+
+	system, err := constructor(dir)
+	require.NoError(t, err)
+
+	feedSystem, ok := system.(dbbenchmark.FeedDatabaseSystem)
+	if !ok {
+		require.NoError(t, system.Close())
+		t.Skip("feeds are not supported")
+	}
+
+	authors := newAuthors(2)
+	values := appendToFeeds(t, feedSystem, authors, 20)
+
+	require.NoError(t, system.Sync())
+	require.NoError(t, system.Close())
+
+	feedSystem = newFeedDatabaseSystem(t, dir, constructor)
+
+	for _, author := range authors {
+		require.Equal(t, values[author], iterateFeed(t, feedSystem, author, 0, 100))
+	}
+}
+
+func testFeedsDeleteFeed(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newFeedDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	authors := newAuthors(2)
+	values := appendToFeeds(t, system, authors, 20)
+
+	err := system.UpdateFeeds(func(updater dbbenchmark.FeedUpdater) error {
+		return updater.DeleteFeed(authors[0])
+	})
+	require.NoError(t, err)
+
+	requireLastFeedSequence(t, system, authors[0], 0, false)
+	require.Empty(t, iterateFeed(t, system, authors[0], 0, 100))
+	require.Equal(t, values[authors[1]], iterateFeed(t, system, authors[1], 0, 100))
+
+	err = system.UpdateFeeds(func(updater dbbenchmark.FeedUpdater) error {
+		seq, err := updater.AppendToFeed(authors[0], fixtures.RandomBytes(10))
+		if err != nil {
+			return errors.Wrap(err, "error calling append to feed")
+		}
+		require.Equal(t, dbbenchmark.Sequence(0), seq, "sequences should start from zero after deleting the feed")
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = system.UpdateFeeds(func(updater dbbenchmark.FeedUpdater) error {
+		return updater.DeleteFeed(newAuthors(1)[0])
+	})
+	require.NoError(t, err, "deleting a feed which doesn't exist shouldn't fail")
+}
+
+func newFeedDatabaseSystem(t *testing.T, dir string, constructor dbbenchmark.DatabaseSystemConstructor) dbbenchmark.FeedDatabaseSystem {
+	system := newDatabaseSystem(t, dir, constructor)
+
+	feedSystem, ok := system.(dbbenchmark.FeedDatabaseSystem)
+	if !ok {
+		t.Skip("feeds are not supported")
+	}
+
+	return feedSystem
+}
+
+func newAuthors(n int) []dbbenchmark.Author {
+	var authors []dbbenchmark.Author
+	for i := 0; i < n; i++ {
+		var author dbbenchmark.Author
+		copy(author[:], fixtures.RandomBytes(len(author)))
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+// appendToFeeds appends n values in total spreading them evenly between the
+// feeds of the given authors.
+func appendToFeeds(t *testing.T, system dbbenchmark.FeedDatabaseSystem, authors []dbbenchmark.Author, n int) map[dbbenchmark.Author][][]byte {
+	values := make(map[dbbenchmark.Author][][]byte)
+
+	err := system.UpdateFeeds(func(updater dbbenchmark.FeedUpdater) error {
+		for i := 0; i < n; i++ {
+			author := authors[i%len(authors)]
+			value := fixtures.RandomBytes(100)
+
+			seq, err := updater.AppendToFeed(author, value)
+			if err != nil {
+				return errors.Wrap(err, "error calling append to feed")
+			}
+
+			require.Equal(t, dbbenchmark.Sequence(len(values[author])), seq)
+			values[author] = append(values[author], value)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	return values
+}
+
+func iterateFeed(t *testing.T, system dbbenchmark.FeedDatabaseSystem, author dbbenchmark.Author, from dbbenchmark.Sequence, limit int) [][]byte {
+	var values [][]byte
+
+	err := system.ReadFeeds(func(reader dbbenchmark.FeedReader) error {
+		return reader.IterateFeed(author, from, limit, func(item dbbenchmark.Item) error {
+			values = append(values, append([]byte(nil), item.Value...))
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	return values
+}
+
+func requireLastFeedSequence(t *testing.T, system dbbenchmark.FeedDatabaseSystem, author dbbenchmark.Author, expectedSequence dbbenchmark.Sequence, expectedOk bool) {
+	err := system.ReadFeeds(func(reader dbbenchmark.FeedReader) error {
+		seq, ok, err := reader.LastFeedSequence(author)
+		if err != nil {
+			return errors.Wrap(err, "error calling last feed sequence")
+		}
+		require.Equal(t, expectedOk, ok)
+		require.Equal(t, expectedSequence, seq)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testKeyIndexAppendAndGet(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newKeyIndexDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	appendValues(t, system, 5)
+	keys, values := appendValuesWithKeys(t, system, 10)
+	appendValues(t, system, 5)
+
+	for i, key := range keys {
+		requireValueByKey(t, system, key, dbbenchmark.Sequence(5+i), values[i])
+	}
+}
+
+func testKeyIndexGetMissing(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newKeyIndexDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	key := newKeys(1)[0]
+
+	err := system.ReadWithKeys(func(reader dbbenchmark.KeyIndexReader) error {
+		_, err := reader.GetSequence(key)
+		return err
+	})
+	require.ErrorIs(t, err, dbbenchmark.ErrNotFound)
+
+	appendValuesWithKeys(t, system, 10)
+
+	err = system.ReadWithKeys(func(reader dbbenchmark.KeyIndexReader) error {
+		_, err := reader.GetSequence(key)
+		return err
+	})
+	require.ErrorIs(t, err, dbbenchmark.ErrNotFound)
+}
+
+func testKeyIndexPersistence(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	dir := fixtures.Directory(t, "")
+
+	system, err := constructor(dir)
+	require.NoError(t, err)
+
+	keyIndexSystem, ok := system.(dbbenchmark.KeyIndexDatabaseSystem)
+	if !ok {
+		require.NoError(t, system.Close())
+		t.Skip("key index is not supported")
+	}
+
+	keys, values := appendValuesWithKeys(t, keyIndexSystem, 20)
+
+	require.NoError(t, system.Sync())
+	require.NoError(t, system.Close())
+
+	keyIndexSystem = newKeyIndexDatabaseSystem(t, dir, constructor)
+
+	for i, key := range keys {
+		requireValueByKey(t, keyIndexSystem, key, dbbenchmark.Sequence(i), values[i])
+	}
+}
+
+func testKeyIndexDelete(t *testing.T, constructor dbbenchmark.DatabaseSystemConstructor) {
+	system := newKeyIndexDatabaseSystem(t, fixtures.Directory(t, ""), constructor)
+
+	keys, values := appendValuesWithKeys(t, system, 10)
+
+	err := system.Update(func(updater dbbenchmark.Updater) error {
+		if err := updater.Delete(2); err != nil {
+			return errors.Wrap(err, "error calling delete")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = system.UpdateWithKeys(func(updater dbbenchmark.KeyIndexUpdater) error {
+		if err := updater.DeleteRange(5, 7); err != nil {
+			return errors.Wrap(err, "error calling delete range")
+		}
+		if err := updater.Delete(9); err != nil {
+			return errors.Wrap(err, "error calling delete")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	deleted := map[int]bool{2: true, 5: true, 6: true, 9: true}
+
+	for i, key := range keys {
+		if !deleted[i] {
+			requireValueByKey(t, system, key, dbbenchmark.Sequence(i), values[i])
+			continue
+		}
+
+		err := system.ReadWithKeys(func(reader dbbenchmark.KeyIndexReader) error {
+			_, err := reader.GetSequence(key)
+			return err
+		})
+		require.ErrorIs(t, err, dbbenchmark.ErrNotFound, "key of a deleted value should be removed from the index")
+	}
+}
+
+func newKeyIndexDatabaseSystem(t *testing.T, dir string, constructor dbbenchmark.DatabaseSystemConstructor) dbbenchmark.KeyIndexDatabaseSystem {
+	system := newDatabaseSystem(t, dir, constructor)
+
+	keyIndexSystem, ok := system.(dbbenchmark.KeyIndexDatabaseSystem)
+	if !ok {
+		t.Skip("key index is not supported")
+	}
+
+	return keyIndexSystem
+}
+
+func newKeys(n int) []dbbenchmark.Key {
+	var keys []dbbenchmark.Key
+	for i := 0; i < n; i++ {
+		var key dbbenchmark.Key
+		copy(key[:], fixtures.RandomBytes(len(key)))
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func appendValuesWithKeys(t *testing.T, system dbbenchmark.KeyIndexDatabaseSystem, n int) ([]dbbenchmark.Key, [][]byte) {
+	keys := newKeys(n)
+
+	var values [][]byte
+
+	err := system.UpdateWithKeys(func(updater dbbenchmark.KeyIndexUpdater) error {
+		for _, key := range keys {
+			value := fixtures.RandomBytes(100)
+			if _, err := updater.AppendWithKey(key, value); err != nil {
+				return errors.Wrap(err, "error calling append with key")
+			}
+			values = append(values, value)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	return keys, values
+}
+
+func requireValueByKey(t *testing.T, system dbbenchmark.KeyIndexDatabaseSystem, key dbbenchmark.Key, expectedSequence dbbenchmark.Sequence, expectedValue []byte) {
+	err := system.ReadWithKeys(func(reader dbbenchmark.KeyIndexReader) error {
+		seq, err := reader.GetSequence(key)
+		if err != nil {
+			return errors.Wrap(err, "error calling get sequence")
+		}
+
+		require.Equal(t, expectedSequence, seq)
+
+		value, err := reader.Get(seq)
+		if err != nil {
+			return errors.Wrap(err, "error calling get")
+		}
+
+		require.Equal(t, expectedValue, value)
+		return nil
+	})
+	require.NoError(t, err)
+}
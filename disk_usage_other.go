@@ -0,0 +1,11 @@
+//go:build !unix
+
+package db_benchmark
+
+import (
+	"os"
+)
+
+func diskUsage(info os.FileInfo) int64 {
+	return info.Size()
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package db_benchmark
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskUsage returns the number of bytes allocated on disk for the file. This
+// can be smaller than its size if the file is sparse, badger preallocates
+// sparse value log files.
+func diskUsage(info os.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+	return stat.Blocks * 512
+}
@@ -0,0 +1,120 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/boreq/errors"
+)
+
+const (
+	// DurabilityNone never syncs, committed transactions can be lost if the
+	// machine crashes.
+	DurabilityNone = "no_sync"
+
+	// DurabilityGroupCommit syncs committed transactions before Update
+	// returns but transactions committed concurrently share a single sync.
+	DurabilityGroupCommit = "group_commit"
+
+	// DurabilityFsync syncs every transaction before Update returns.
+	DurabilityFsync = "fsync"
+)
+
+const DefaultDurability = DurabilityFsync
+
+func checkDurability(durability string) error {
+	switch durability {
+	case DurabilityNone, DurabilityGroupCommit, DurabilityFsync:
+		return nil
+	default:
+		return fmt.Errorf("unknown durability '%s'", durability)
+	}
+}
+
+// groupCommitter calls a sync function after transactions are committed so
+// that transactions committed concurrently share a single sync.
+type groupCommitter struct {
+	fn func() error
+
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	syncing  bool
+	started  uint64
+	finished uint64
+	err      error
+}
+
+func newGroupCommitter(fn func() error) *groupCommitter {
+	g := &groupCommitter{fn: fn}
+	g.cond = sync.NewCond(&g.mutex)
+	return g
+}
+
+// Sync returns once a sync which started after Sync was called finished.
+// The error returned by that sync is returned.
+func (g *groupCommitter) Sync() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// a sync which is already in progress might have started before the
+	// transaction was committed
+	target := g.started + 1
+
+	for g.finished < target {
+		if g.syncing {
+			g.cond.Wait()
+			continue
+		}
+
+		g.syncing = true
+		g.started++
+		n := g.started
+
+		g.mutex.Unlock()
+		err := g.fn()
+		g.mutex.Lock()
+
+		g.syncing = false
+		g.finished = n
+		g.err = err
+		g.cond.Broadcast()
+	}
+
+	return g.err
+}
+
+// syncFiles calls fsync on all regular files located directly in the
+// directory. Nothing is done if the directory doesn't exist.
+func syncFiles(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "error reading the directory")
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		if err := syncFile(path.Join(dir, entry.Name())); err != nil {
+			return errors.Wrapf(err, "error syncing '%s'", entry.Name())
+		}
+	}
+
+	return nil
+}
+
+func syncFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return errors.Wrap(err, "error opening the file")
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
@@ -0,0 +1,46 @@
+package db_benchmark
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupCommitterSharesSyncs(t *testing.T) {
+	const callers = 100
+
+	arrived := &sync.WaitGroup{}
+	arrived.Add(callers)
+
+	var syncs int
+
+	g := newGroupCommitter(func() error {
+		// the first sync blocks until all callers called Sync so that
+		// they have to wait for the next sync
+		if syncs == 0 {
+			arrived.Wait()
+		}
+		syncs++
+		return nil
+	})
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			arrived.Done()
+			require.NoError(t, g.Sync())
+		}()
+	}
+
+	wg.Wait()
+
+	require.Positive(t, syncs)
+	require.Less(t, syncs, callers, "callers waiting for the same sync should share it")
+
+	before := syncs
+	require.NoError(t, g.Sync())
+	require.Equal(t, before+1, syncs, "a sync must start after sync is called")
+}
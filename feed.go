@@ -0,0 +1,35 @@
+package db_benchmark
+
+// Author identifies a feed, in SSB this is the ed25519 public key of the
+// author of the feed.
+type Author [32]byte
+
+// FeedDatabaseSystem is implemented by database systems which in addition to
+// the global log can store multiple feeds, each one with its own sequence
+// counter. Feeds are stored separately from the global log.
+type FeedDatabaseSystem interface {
+	DatabaseSystem
+	UpdateFeeds(func(updater FeedUpdater) error) error
+	ReadFeeds(func(reader FeedReader) error) error
+}
+
+type FeedUpdater interface {
+	// AppendToFeed appends the value to the feed of the given author and
+	// returns the sequence which was assigned to it. Sequences start at 0
+	// in every feed.
+	AppendToFeed(author Author, value []byte) (Sequence, error)
+
+	// DeleteFeed removes all values from the feed of the given author
+	// and resets its sequence counter.
+	DeleteFeed(author Author) error
+}
+
+type FeedReader interface {
+	// IterateFeed calls fn for at most limit values from the feed of the
+	// given author starting with the value with the given sequence.
+	IterateFeed(author Author, from Sequence, limit int, fn func(item Item) error) error
+
+	// LastFeedSequence returns the last sequence which was assigned in the
+	// feed of the given author. False is returned if the feed is empty.
+	LastFeedSequence(author Author) (Sequence, bool, error)
+}
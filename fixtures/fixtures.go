@@ -2,6 +2,7 @@ package fixtures
 
 import (
 	"crypto/rand"
+	mathrand "math/rand"
 	"os"
 	"testing"
 )
@@ -31,3 +32,14 @@ func RandomBytes(n int) []byte {
 	}
 	return r
 }
+
+// RandomBytesFrom returns n bytes read from the provided source so that the
+// same bytes are returned for the same seed.
+func RandomBytesFrom(rnd *mathrand.Rand, n int) []byte {
+	r := make([]byte, n)
+	_, err := rnd.Read(r)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
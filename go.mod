@@ -11,6 +11,7 @@ require (
 	github.com/wcharczuk/go-chart/v2 v2.1.0
 	go.cryptoscope.co/margaret v0.4.3
 	go.etcd.io/bbolt v1.3.7
+	golang.org/x/sys v0.5.0
 	golang.org/x/tools v0.5.0
 )
 
@@ -34,7 +35,6 @@ require (
 	go.opencensus.io v0.22.5 // indirect
 	golang.org/x/image v0.3.0 // indirect
 	golang.org/x/net v0.5.0 // indirect
-	golang.org/x/sys v0.5.0 // indirect
 	google.golang.org/protobuf v1.26.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,154 @@
+// Package histogram implements a histogram which records values with a
+// bounded relative error in the spirit of HdrHistogram. Values are placed in
+// buckets whose width grows with the magnitude of the values so that the
+// memory used doesn't depend on the range of the recorded values.
+package histogram
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	// subBucketBits controls the precision of the histogram, values are
+	// recorded with a relative error smaller than 1/2^(subBucketBits-1).
+	subBucketBits      = 7
+	subBucketCount     = 1 << subBucketBits
+	subBucketHalfCount = subBucketCount / 2
+)
+
+// Histogram records non-negative values. The zero value is an empty
+// histogram ready to use. Histogram isn't safe for concurrent use.
+type Histogram struct {
+	counts []int64
+	count  int64
+	min    int64
+	max    int64
+}
+
+// Record adds the value to the histogram. Negative values are recorded as
+// zero.
+func (h *Histogram) Record(value int64) {
+	if value < 0 {
+		value = 0
+	}
+
+	i := index(value)
+	if i >= len(h.counts) {
+		counts := make([]int64, i+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[i]++
+
+	if h.count == 0 || value < h.min {
+		h.min = value
+	}
+
+	if value > h.max {
+		h.max = value
+	}
+
+	h.count++
+}
+
+// Merge adds all values recorded in the other histogram to this histogram.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.count == 0 {
+		return
+	}
+
+	if len(other.counts) > len(h.counts) {
+		counts := make([]int64, len(other.counts))
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+
+	if other.max > h.max {
+		h.max = other.max
+	}
+
+	h.count += other.count
+}
+
+// Count returns the number of recorded values.
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+// Min returns the smallest recorded value or zero if the histogram is empty.
+func (h *Histogram) Min() int64 {
+	return h.min
+}
+
+// Max returns the largest recorded value or zero if the histogram is empty.
+func (h *Histogram) Max() int64 {
+	return h.max
+}
+
+// ValueAtPercentile returns the value below or at which the given percentage
+// of the recorded values fall, percentile must be in range [0, 100]. The
+// returned value is the upper bound of the bucket in which the percentile
+// falls but is never larger than the largest recorded value. Zero is
+// returned if the histogram is empty.
+func (h *Histogram) ValueAtPercentile(percentile float64) int64 {
+	if h.count == 0 {
+		return 0
+	}
+
+	target := int64(math.Ceil(percentile / 100 * float64(h.count)))
+	if target < 1 {
+		target = 1
+	}
+
+	var seen int64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= target {
+			v := highestEquivalentValue(i)
+			if v > h.max {
+				return h.max
+			}
+			if v < h.min {
+				return h.min
+			}
+			return v
+		}
+	}
+
+	return h.max
+}
+
+// index returns the index of the bucket in which the value is recorded.
+// Values smaller than subBucketCount have their own buckets, larger values
+// share buckets with the values which have the same subBucketBits most
+// significant bits.
+func index(value int64) int {
+	if value < subBucketCount {
+		return int(value)
+	}
+
+	shift := bits.Len64(uint64(value)) - subBucketBits
+	sub := int(value >> shift)
+	return subBucketCount + (shift-1)*subBucketHalfCount + (sub - subBucketHalfCount)
+}
+
+// highestEquivalentValue returns the largest value which is recorded in the
+// bucket with the given index.
+func highestEquivalentValue(i int) int64 {
+	if i < subBucketCount {
+		return int64(i)
+	}
+
+	shift := (i-subBucketCount)/subBucketHalfCount + 1
+	sub := uint64((i-subBucketCount)%subBucketHalfCount + subBucketHalfCount)
+	return int64((sub+1)<<shift - 1)
+}
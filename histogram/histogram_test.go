@@ -0,0 +1,88 @@
+package histogram
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmpty(t *testing.T) {
+	var h Histogram
+
+	require.Equal(t, int64(0), h.Count())
+	require.Equal(t, int64(0), h.Max())
+	require.Equal(t, int64(0), h.ValueAtPercentile(50))
+}
+
+func TestSmallValuesAreExact(t *testing.T) {
+	var h Histogram
+
+	for i := int64(1); i <= 100; i++ {
+		h.Record(i)
+	}
+
+	require.Equal(t, int64(100), h.Count())
+	require.Equal(t, int64(1), h.Min())
+	require.Equal(t, int64(100), h.Max())
+	require.Equal(t, int64(1), h.ValueAtPercentile(0))
+	require.Equal(t, int64(50), h.ValueAtPercentile(50))
+	require.Equal(t, int64(90), h.ValueAtPercentile(90))
+	require.Equal(t, int64(99), h.ValueAtPercentile(99))
+	require.Equal(t, int64(100), h.ValueAtPercentile(100))
+}
+
+func TestRelativeError(t *testing.T) {
+	var h Histogram
+	var values []int64
+
+	for i := 0; i < 100000; i++ {
+		v := int64(math.Exp(rand.Float64() * 30))
+		values = append(values, v)
+		h.Record(v)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	for _, percentile := range []float64{1, 50, 90, 99, 99.9, 100} {
+		expected := values[int(math.Ceil(percentile/100*float64(len(values))))-1]
+		actual := h.ValueAtPercentile(percentile)
+
+		require.GreaterOrEqual(t, actual, expected, "percentile %f", percentile)
+		require.LessOrEqual(t, float64(actual-expected), float64(expected)/subBucketHalfCount, "percentile %f", percentile)
+	}
+
+	require.Equal(t, values[len(values)-1], h.Max())
+}
+
+func TestIndex(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 129, 255, 256, 1000, 1 << 40, math.MaxInt64} {
+		i := index(v)
+		require.GreaterOrEqual(t, highestEquivalentValue(i), v)
+		if i > 0 {
+			require.Less(t, highestEquivalentValue(i-1), v)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var a, b, expected Histogram
+
+	for i := int64(0); i < 1000; i++ {
+		a.Record(i * 3)
+		expected.Record(i * 3)
+	}
+
+	for i := int64(0); i < 500; i++ {
+		b.Record(i * 1000)
+		expected.Record(i * 1000)
+	}
+
+	a.Merge(&b)
+
+	require.Equal(t, expected, a)
+}
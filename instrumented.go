@@ -0,0 +1,337 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boreq/db_benchmark/histogram"
+)
+
+// Operations which latencies are recorded by InstrumentedDatabaseSystem.
+const (
+	OperationAppend      = "append"
+	OperationDelete      = "delete"
+	OperationGet         = "get"
+	OperationIterate     = "iterate"
+	OperationGetSequence = "get_sequence"
+)
+
+// LatencyPercentiles are the percentiles of latencies which are reported
+// for each operation in addition to the maximum latency.
+var LatencyPercentiles = []float64{50, 90, 99, 99.9}
+
+// InstrumentedDatabaseSystem wraps a database system and records the latency
+// of every call to Updater.Append, Updater.Delete, Updater.DeleteRange,
+// Reader.Get, Reader.Iterate and Reader.IterateRange. Deleting a range is
+// recorded as a single operation. Iterations are recorded as a single operation which
+// includes the time spent in the provided function. Calls made using the
+// feeds and the key index are recorded if the database system is obtained
+// using instrumentFeeds and instrumentKeyIndex.
+type InstrumentedDatabaseSystem struct {
+	DatabaseSystem
+
+	// recorderPool hands out recorders so that goroutines calling the
+	// database system concurrently don't contend on a single lock. All
+	// recorders which were created are listed in recorders so that they
+	// can be merged even if the pool dropped them.
+	recorderPool sync.Pool
+	mutex        sync.Mutex
+	recorders    []*latencyRecorder
+}
+
+func NewInstrumentedDatabaseSystem(databaseSystem DatabaseSystem) *InstrumentedDatabaseSystem {
+	s := &InstrumentedDatabaseSystem{
+		DatabaseSystem: databaseSystem,
+	}
+	s.recorderPool.New = func() any {
+		return s.newRecorder()
+	}
+	return s
+}
+
+func (s *InstrumentedDatabaseSystem) Update(fn func(updater Updater) error) error {
+	return s.DatabaseSystem.Update(func(updater Updater) error {
+		return fn(&instrumentedUpdater{Updater: updater, s: s})
+	})
+}
+
+func (s *InstrumentedDatabaseSystem) Read(fn func(reader Reader) error) error {
+	return s.DatabaseSystem.Read(func(reader Reader) error {
+		return fn(&instrumentedReader{Reader: reader, s: s})
+	})
+}
+
+// Unwrap returns the wrapped database system which should be used to check
+// if the database system implements optional interfaces.
+func (s *InstrumentedDatabaseSystem) Unwrap() DatabaseSystem {
+	return s.DatabaseSystem
+}
+
+// Latencies returns copies of histograms of latencies in nanoseconds keyed by
+// the name of the operation.
+func (s *InstrumentedDatabaseSystem) Latencies() map[string]*histogram.Histogram {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	latencies := make(map[string]*histogram.Histogram)
+	for _, recorder := range s.recorders {
+		recorder.mergeInto(latencies)
+	}
+	return latencies
+}
+
+func (s *InstrumentedDatabaseSystem) record(operation string, start time.Time) {
+	duration := time.Since(start)
+
+	recorder := s.recorderPool.Get().(*latencyRecorder)
+	recorder.record(operation, duration)
+	s.recorderPool.Put(recorder)
+}
+
+func (s *InstrumentedDatabaseSystem) newRecorder() *latencyRecorder {
+	recorder := &latencyRecorder{
+		histograms: make(map[string]*histogram.Histogram),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.recorders = append(s.recorders, recorder)
+	return recorder
+}
+
+// latencyRecorder is used by one goroutine at a time, its mutex is only
+// contended when the latencies are merged.
+type latencyRecorder struct {
+	mutex      sync.Mutex
+	histograms map[string]*histogram.Histogram
+}
+
+func (r *latencyRecorder) record(operation string, duration time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	h, ok := r.histograms[operation]
+	if !ok {
+		h = &histogram.Histogram{}
+		r.histograms[operation] = h
+	}
+	h.Record(int64(duration))
+}
+
+func (r *latencyRecorder) mergeInto(latencies map[string]*histogram.Histogram) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for operation, h := range r.histograms {
+		v, ok := latencies[operation]
+		if !ok {
+			v = &histogram.Histogram{}
+			latencies[operation] = v
+		}
+		v.Merge(h)
+	}
+}
+
+// recordLatency records the latency of an operation which isn't a single
+// call to the database system if the database system is instrumented.
+func recordLatency(databaseSystem DatabaseSystem, operation string, start time.Time) {
+	if v, ok := databaseSystem.(*InstrumentedDatabaseSystem); ok {
+		v.record(operation, start)
+	}
+}
+
+type instrumentedUpdater struct {
+	Updater
+	s *InstrumentedDatabaseSystem
+}
+
+func (u *instrumentedUpdater) Append(value []byte) (Sequence, error) {
+	defer u.s.record(OperationAppend, time.Now())
+	return u.Updater.Append(value)
+}
+
+func (u *instrumentedUpdater) Delete(seq Sequence) error {
+	defer u.s.record(OperationDelete, time.Now())
+	return u.Updater.Delete(seq)
+}
+
+func (u *instrumentedUpdater) DeleteRange(start, end Sequence) error {
+	defer u.s.record(OperationDelete, time.Now())
+	return u.Updater.DeleteRange(start, end)
+}
+
+type instrumentedReader struct {
+	Reader
+	s *InstrumentedDatabaseSystem
+}
+
+func (r *instrumentedReader) Get(seq Sequence) ([]byte, error) {
+	defer r.s.record(OperationGet, time.Now())
+	return r.Reader.Get(seq)
+}
+
+func (r *instrumentedReader) Iterate(start Sequence, limit int, fn func(item Item) error) error {
+	defer r.s.record(OperationIterate, time.Now())
+	return r.Reader.Iterate(start, limit, fn)
+}
+
+func (r *instrumentedReader) IterateRange(start, end Sequence, direction Direction, fn func(item Item) error) error {
+	defer r.s.record(OperationIterate, time.Now())
+	return r.Reader.IterateRange(start, end, direction, fn)
+}
+
+// instrumentFeeds returns feeds which record the latencies of
+// FeedUpdater.AppendToFeed as appends and of FeedReader.IterateFeed as
+// iterations if the database system is instrumented. Otherwise feeds are
+// returned unchanged.
+func instrumentFeeds(databaseSystem DatabaseSystem, feeds FeedDatabaseSystem) FeedDatabaseSystem {
+	if v, ok := databaseSystem.(*InstrumentedDatabaseSystem); ok {
+		return &instrumentedFeedDatabaseSystem{FeedDatabaseSystem: feeds, s: v}
+	}
+	return feeds
+}
+
+type instrumentedFeedDatabaseSystem struct {
+	FeedDatabaseSystem
+	s *InstrumentedDatabaseSystem
+}
+
+func (f *instrumentedFeedDatabaseSystem) Update(fn func(updater Updater) error) error {
+	return f.s.Update(fn)
+}
+
+func (f *instrumentedFeedDatabaseSystem) Read(fn func(reader Reader) error) error {
+	return f.s.Read(fn)
+}
+
+func (f *instrumentedFeedDatabaseSystem) UpdateFeeds(fn func(updater FeedUpdater) error) error {
+	return f.FeedDatabaseSystem.UpdateFeeds(func(updater FeedUpdater) error {
+		return fn(&instrumentedFeedUpdater{FeedUpdater: updater, s: f.s})
+	})
+}
+
+func (f *instrumentedFeedDatabaseSystem) ReadFeeds(fn func(reader FeedReader) error) error {
+	return f.FeedDatabaseSystem.ReadFeeds(func(reader FeedReader) error {
+		return fn(&instrumentedFeedReader{FeedReader: reader, s: f.s})
+	})
+}
+
+type instrumentedFeedUpdater struct {
+	FeedUpdater
+	s *InstrumentedDatabaseSystem
+}
+
+func (u *instrumentedFeedUpdater) AppendToFeed(author Author, value []byte) (Sequence, error) {
+	defer u.s.record(OperationAppend, time.Now())
+	return u.FeedUpdater.AppendToFeed(author, value)
+}
+
+type instrumentedFeedReader struct {
+	FeedReader
+	s *InstrumentedDatabaseSystem
+}
+
+func (r *instrumentedFeedReader) IterateFeed(author Author, from Sequence, limit int, fn func(item Item) error) error {
+	defer r.s.record(OperationIterate, time.Now())
+	return r.FeedReader.IterateFeed(author, from, limit, fn)
+}
+
+// instrumentKeyIndex returns a key index which records the latencies of
+// KeyIndexUpdater.AppendWithKey as appends and of KeyIndexReader.GetSequence
+// as a separate operation in addition to the calls recorded by
+// InstrumentedDatabaseSystem if the database system is instrumented.
+// Otherwise keyIndex is returned unchanged.
+func instrumentKeyIndex(databaseSystem DatabaseSystem, keyIndex KeyIndexDatabaseSystem) KeyIndexDatabaseSystem {
+	if v, ok := databaseSystem.(*InstrumentedDatabaseSystem); ok {
+		return &instrumentedKeyIndexDatabaseSystem{KeyIndexDatabaseSystem: keyIndex, s: v}
+	}
+	return keyIndex
+}
+
+type instrumentedKeyIndexDatabaseSystem struct {
+	KeyIndexDatabaseSystem
+	s *InstrumentedDatabaseSystem
+}
+
+func (k *instrumentedKeyIndexDatabaseSystem) Update(fn func(updater Updater) error) error {
+	return k.s.Update(fn)
+}
+
+func (k *instrumentedKeyIndexDatabaseSystem) Read(fn func(reader Reader) error) error {
+	return k.s.Read(fn)
+}
+
+func (k *instrumentedKeyIndexDatabaseSystem) UpdateWithKeys(fn func(updater KeyIndexUpdater) error) error {
+	return k.KeyIndexDatabaseSystem.UpdateWithKeys(func(updater KeyIndexUpdater) error {
+		return fn(&instrumentedKeyIndexUpdater{
+			instrumentedUpdater: &instrumentedUpdater{Updater: updater, s: k.s},
+			updater:             updater,
+		})
+	})
+}
+
+func (k *instrumentedKeyIndexDatabaseSystem) ReadWithKeys(fn func(reader KeyIndexReader) error) error {
+	return k.KeyIndexDatabaseSystem.ReadWithKeys(func(reader KeyIndexReader) error {
+		return fn(&instrumentedKeyIndexReader{
+			instrumentedReader: &instrumentedReader{Reader: reader, s: k.s},
+			reader:             reader,
+		})
+	})
+}
+
+type instrumentedKeyIndexUpdater struct {
+	*instrumentedUpdater
+	updater KeyIndexUpdater
+}
+
+func (u *instrumentedKeyIndexUpdater) AppendWithKey(key Key, value []byte) (Sequence, error) {
+	defer u.s.record(OperationAppend, time.Now())
+	return u.updater.AppendWithKey(key, value)
+}
+
+type instrumentedKeyIndexReader struct {
+	*instrumentedReader
+	reader KeyIndexReader
+}
+
+func (r *instrumentedKeyIndexReader) GetSequence(key Key) (Sequence, error) {
+	defer r.s.record(OperationGetSequence, time.Now())
+	return r.reader.GetSequence(key)
+}
+
+// unwrap returns the database system wrapped by InstrumentedDatabaseSystem
+// or the provided database system if it isn't wrapped.
+func unwrap(databaseSystem DatabaseSystem) DatabaseSystem {
+	if v, ok := databaseSystem.(*InstrumentedDatabaseSystem); ok {
+		return v.Unwrap()
+	}
+	return databaseSystem
+}
+
+// reportLatencies reports the percentiles and the maximum of latencies of
+// each operation using units such as "get_p99_ns" and "get_max_ns".
+func reportLatencies(b *testing.B, latencies map[string]*histogram.Histogram) {
+	var operations []string
+	for operation := range latencies {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	for _, operation := range operations {
+		h := latencies[operation]
+		for _, percentile := range LatencyPercentiles {
+			b.ReportMetric(float64(h.ValueAtPercentile(percentile)), LatencyUnit(operation, fmt.Sprintf("p%g", percentile)))
+		}
+		b.ReportMetric(float64(h.Max()), LatencyUnit(operation, "max"))
+	}
+}
+
+// LatencyUnit returns the unit used to report a latency statistic of an
+// operation, for example "get_p99.9_ns".
+func LatencyUnit(operation, statistic string) string {
+	return fmt.Sprintf("%s_%s_ns", operation, statistic)
+}
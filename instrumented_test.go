@@ -0,0 +1,125 @@
+package db_benchmark
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedDatabaseSystemRecordsConcurrentOperations(t *testing.T) {
+	const (
+		goroutines           = 8
+		operationsPerRoutine = 100
+	)
+
+	system, err := NewTestedDatabaseSystem(DatabaseSystemConfig{
+		Type:            BoltDatabaseSystemType,
+		Codec:           CodecNone,
+		TransactionSize: DefaultTransactionSize,
+		Durability:      DurabilityNone,
+	})
+	require.NoError(t, err)
+
+	databaseSystem, err := system.DatabaseSystemConstructor(fixtures.Directory(t, ""))
+	require.NoError(t, err)
+	defer databaseSystem.Close()
+
+	instrumentedSystem := NewInstrumentedDatabaseSystem(databaseSystem)
+
+	err = instrumentedSystem.Update(func(updater Updater) error {
+		if _, err := updater.Append([]byte("value")); err != nil {
+			return err
+		}
+		if err := updater.Delete(1); err != nil {
+			return err
+		}
+		return updater.DeleteRange(1, 3)
+	})
+	require.NoError(t, err)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := instrumentedSystem.Read(func(reader Reader) error {
+				for j := 0; j < operationsPerRoutine; j++ {
+					if _, err := reader.Get(0); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	latencies := instrumentedSystem.Latencies()
+	require.EqualValues(t, 1, latencies[OperationAppend].Count())
+	require.EqualValues(t, 2, latencies[OperationDelete].Count())
+	require.EqualValues(t, goroutines*operationsPerRoutine, latencies[OperationGet].Count())
+}
+
+func TestInstrumentedDatabaseSystemRecordsFeedAndKeyIndexOperations(t *testing.T) {
+	system, err := NewTestedDatabaseSystem(DatabaseSystemConfig{
+		Type:            BoltDatabaseSystemType,
+		Codec:           CodecNone,
+		TransactionSize: DefaultTransactionSize,
+		Durability:      DurabilityNone,
+	})
+	require.NoError(t, err)
+
+	databaseSystem, err := system.DatabaseSystemConstructor(fixtures.Directory(t, ""))
+	require.NoError(t, err)
+	defer databaseSystem.Close()
+
+	instrumentedSystem := NewInstrumentedDatabaseSystem(databaseSystem)
+
+	feeds, err := asFeedDatabaseSystem(instrumentedSystem)
+	require.NoError(t, err)
+
+	err = feeds.UpdateFeeds(func(updater FeedUpdater) error {
+		_, err := updater.AppendToFeed(Author{1}, []byte("value"))
+		return err
+	})
+	require.NoError(t, err)
+
+	err = feeds.ReadFeeds(func(reader FeedReader) error {
+		return reader.IterateFeed(Author{1}, 0, 1, func(item Item) error {
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	keyIndex, err := asKeyIndexDatabaseSystem(instrumentedSystem)
+	require.NoError(t, err)
+
+	err = keyIndex.UpdateWithKeys(func(updater KeyIndexUpdater) error {
+		if _, err := updater.AppendWithKey(Key{1}, []byte("value")); err != nil {
+			return err
+		}
+		_, err := updater.Append([]byte("value"))
+		return err
+	})
+	require.NoError(t, err)
+
+	err = keyIndex.ReadWithKeys(func(reader KeyIndexReader) error {
+		sequence, err := reader.GetSequence(Key{1})
+		if err != nil {
+			return err
+		}
+		_, err = reader.Get(sequence)
+		return err
+	})
+	require.NoError(t, err)
+
+	latencies := instrumentedSystem.Latencies()
+	require.EqualValues(t, 3, latencies[OperationAppend].Count())
+	require.EqualValues(t, 1, latencies[OperationIterate].Count())
+	require.EqualValues(t, 1, latencies[OperationGetSequence].Count())
+	require.EqualValues(t, 1, latencies[OperationGet].Count())
+}
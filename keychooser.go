@@ -0,0 +1,232 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/boreq/errors"
+)
+
+const (
+	KeyChooserUniform = "uniform"
+	KeyChooserZipfian = "zipfian"
+	KeyChooserLatest  = "latest"
+	KeyChooserHotspot = "hotspot"
+
+	DefaultZipfianTheta            = 0.99
+	DefaultHotKeysPercentage       = 20
+	DefaultHotOperationsPercentage = 80
+)
+
+// KeyChooser chooses sequences accessed by the benchmarks which makes it
+// possible to simulate skewed access patterns. Implementations aren't safe
+// for concurrent use.
+type KeyChooser interface {
+	// Name describes the key chooser and its parameters. It is appended
+	// to the names of benchmarks.
+	Name() string
+
+	// Choose returns a sequence in range [0, lastSequence].
+	Choose(rnd *rand.Rand, lastSequence Sequence) Sequence
+}
+
+// UniformKeyChooser chooses all sequences with the same probability.
+type UniformKeyChooser struct {
+}
+
+func NewUniformKeyChooser() UniformKeyChooser {
+	return UniformKeyChooser{}
+}
+
+func (c UniformKeyChooser) Name() string {
+	return KeyChooserUniform
+}
+
+func (c UniformKeyChooser) Choose(rnd *rand.Rand, lastSequence Sequence) Sequence {
+	return Sequence(rnd.Int63n(int64(lastSequence) + 1))
+}
+
+// ZipfianKeyChooser chooses sequences so that their popularity follows the
+// Zipfian distribution in the same way as the zipfian request distribution of
+// YCSB. Popular sequences are scattered over the entire log.
+type ZipfianKeyChooser struct {
+	zipfian *zipfian
+}
+
+func NewZipfianKeyChooser(theta float64) (*ZipfianKeyChooser, error) {
+	zipfian, err := newZipfian(theta)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the zipfian generator")
+	}
+
+	return &ZipfianKeyChooser{
+		zipfian: zipfian,
+	}, nil
+}
+
+func (c *ZipfianKeyChooser) Name() string {
+	return fmt.Sprintf("%s_%g", KeyChooserZipfian, c.zipfian.theta)
+}
+
+func (c *ZipfianKeyChooser) Choose(rnd *rand.Rand, lastSequence Sequence) Sequence {
+	n := uint64(lastSequence) + 1
+	rank := c.zipfian.next(rnd, n)
+	return Sequence(fnvHash64(rank) % n)
+}
+
+// LatestKeyChooser chooses recently appended sequences more often than the
+// old ones, the popularity of sequences ordered from the newest follows the
+// Zipfian distribution.
+type LatestKeyChooser struct {
+	zipfian *zipfian
+}
+
+func NewLatestKeyChooser(theta float64) (*LatestKeyChooser, error) {
+	zipfian, err := newZipfian(theta)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the zipfian generator")
+	}
+
+	return &LatestKeyChooser{
+		zipfian: zipfian,
+	}, nil
+}
+
+func (c *LatestKeyChooser) Name() string {
+	return fmt.Sprintf("%s_%g", KeyChooserLatest, c.zipfian.theta)
+}
+
+func (c *LatestKeyChooser) Choose(rnd *rand.Rand, lastSequence Sequence) Sequence {
+	rank := c.zipfian.next(rnd, uint64(lastSequence)+1)
+	return lastSequence - Sequence(rank)
+}
+
+// HotspotKeyChooser directs the given percentage of operations to a hot set
+// consisting of the given percentage of the most recently appended
+// sequences. Sequences are chosen uniformly within the hot and the cold set.
+type HotspotKeyChooser struct {
+	hotKeysPercentage       int
+	hotOperationsPercentage int
+}
+
+func NewHotspotKeyChooser(hotKeysPercentage, hotOperationsPercentage int) (HotspotKeyChooser, error) {
+	if hotKeysPercentage <= 0 || hotKeysPercentage > 100 {
+		return HotspotKeyChooser{}, errors.New("hot keys percentage must be in range (0, 100]")
+	}
+
+	if hotOperationsPercentage < 0 || hotOperationsPercentage > 100 {
+		return HotspotKeyChooser{}, errors.New("hot operations percentage must be in range [0, 100]")
+	}
+
+	return HotspotKeyChooser{
+		hotKeysPercentage:       hotKeysPercentage,
+		hotOperationsPercentage: hotOperationsPercentage,
+	}, nil
+}
+
+func (c HotspotKeyChooser) Name() string {
+	return fmt.Sprintf("%s_%d_percent_keys_%d_percent_operations", KeyChooserHotspot, c.hotKeysPercentage, c.hotOperationsPercentage)
+}
+
+func (c HotspotKeyChooser) Choose(rnd *rand.Rand, lastSequence Sequence) Sequence {
+	n := int64(lastSequence) + 1
+
+	hot := n * int64(c.hotKeysPercentage) / 100
+	if hot < 1 {
+		hot = 1
+	}
+	cold := n - hot
+
+	if cold == 0 || rnd.Intn(100) < c.hotOperationsPercentage {
+		return Sequence(cold + rnd.Int63n(hot))
+	}
+	return Sequence(rnd.Int63n(cold))
+}
+
+// zipfian generates ranks in range [0, n) where 0 is the most popular rank
+// using the algorithm from "Quickly Generating Billion-Record Synthetic
+// Databases" by Gray et al. which is also used by YCSB. The zeta constant is
+// updated incrementally as n grows.
+type zipfian struct {
+	theta float64
+	alpha float64
+	zeta2 float64
+	n     uint64
+	zetaN float64
+	eta   float64
+}
+
+func newZipfian(theta float64) (*zipfian, error) {
+	if theta <= 0 || theta >= 1 {
+		return nil, errors.New("theta must be in range (0, 1)")
+	}
+
+	return &zipfian{
+		theta: theta,
+		alpha: 1 / (1 - theta),
+		zeta2: 1 + math.Pow(0.5, theta),
+	}, nil
+}
+
+func (z *zipfian) next(rnd *rand.Rand, n uint64) uint64 {
+	if n != z.n {
+		z.resize(n)
+	}
+
+	if n == 1 {
+		return 0
+	}
+
+	u := rnd.Float64()
+	uz := u * z.zetaN
+
+	if uz < 1 {
+		return 0
+	}
+
+	if uz < z.zeta2 {
+		return 1
+	}
+
+	rank := uint64(float64(n) * math.Pow(z.eta*u-z.eta+1, z.alpha))
+	if rank >= n {
+		rank = n - 1
+	}
+	return rank
+}
+
+func (z *zipfian) resize(n uint64) {
+	if n < z.n {
+		z.n = 0
+		z.zetaN = 0
+	}
+
+	for i := z.n + 1; i <= n; i++ {
+		z.zetaN += 1 / math.Pow(float64(i), z.theta)
+	}
+
+	z.n = n
+
+	// eta isn't used if there are less than three ranks as uz is always
+	// smaller than zeta2 in that case
+	if n > 2 {
+		z.eta = (1 - math.Pow(2/float64(n), 1-z.theta)) / (1 - z.zeta2/z.zetaN)
+	}
+}
+
+// fnvHash64 returns the FNV-1a hash of the little-endian bytes of the value.
+func fnvHash64(v uint64) uint64 {
+	const (
+		offsetBasis = 0xcbf29ce484222325
+		prime       = 0x100000001b3
+	)
+
+	hash := uint64(offsetBasis)
+	for i := 0; i < 8; i++ {
+		hash ^= v & 0xff
+		hash *= prime
+		v >>= 8
+	}
+	return hash
+}
@@ -0,0 +1,114 @@
+package db_benchmark
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyChoosers(t *testing.T) {
+	const numberOfSequences = 1000
+	const numberOfChoices = 100000
+
+	zipfian, err := NewZipfianKeyChooser(DefaultZipfianTheta)
+	require.NoError(t, err)
+
+	latest, err := NewLatestKeyChooser(DefaultZipfianTheta)
+	require.NoError(t, err)
+
+	hotspot, err := NewHotspotKeyChooser(10, 90)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		KeyChooser KeyChooser
+		Check      func(t *testing.T, counts []int)
+	}{
+		{
+			KeyChooser: NewUniformKeyChooser(),
+			Check: func(t *testing.T, counts []int) {
+				for _, count := range counts {
+					require.InDelta(t, numberOfChoices/numberOfSequences, count, 50)
+				}
+			},
+		},
+		{
+			KeyChooser: zipfian,
+			Check: func(t *testing.T, counts []int) {
+				sorted := make([]int, len(counts))
+				copy(sorted, counts)
+				sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+				// the most popular sequences are chosen far more often
+				// than the least popular ones
+				require.Greater(t, sorted[0], 50*sorted[len(sorted)/2])
+
+				// popular sequences aren't all at the beginning of the
+				// log
+				require.Less(t, counts[0], sorted[0])
+			},
+		},
+		{
+			KeyChooser: latest,
+			Check: func(t *testing.T, counts []int) {
+				last := counts[numberOfSequences-1]
+				for _, count := range counts[:numberOfSequences-1] {
+					require.Less(t, count, last)
+				}
+				require.Greater(t, sum(counts[numberOfSequences-100:]), sum(counts[:numberOfSequences-100]))
+			},
+		},
+		{
+			KeyChooser: hotspot,
+			Check: func(t *testing.T, counts []int) {
+				hot := sum(counts[numberOfSequences-numberOfSequences/10:])
+				require.InDelta(t, numberOfChoices*90/100, hot, numberOfChoices/100)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.KeyChooser.Name(), func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(1))
+
+			counts := make([]int, numberOfSequences)
+			for i := 0; i < numberOfChoices; i++ {
+				seq := testCase.KeyChooser.Choose(rnd, numberOfSequences-1)
+				require.Less(t, int(seq), numberOfSequences)
+				counts[seq]++
+			}
+
+			testCase.Check(t, counts)
+		})
+	}
+}
+
+func TestKeyChoosersHandleSmallAndGrowingLogs(t *testing.T) {
+	zipfian, err := NewZipfianKeyChooser(DefaultZipfianTheta)
+	require.NoError(t, err)
+
+	latest, err := NewLatestKeyChooser(DefaultZipfianTheta)
+	require.NoError(t, err)
+
+	hotspot, err := NewHotspotKeyChooser(DefaultHotKeysPercentage, DefaultHotOperationsPercentage)
+	require.NoError(t, err)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, keyChooser := range []KeyChooser{NewUniformKeyChooser(), zipfian, latest, hotspot} {
+		for lastSequence := Sequence(0); lastSequence < 100; lastSequence++ {
+			for i := 0; i < 10; i++ {
+				require.LessOrEqual(t, keyChooser.Choose(rnd, lastSequence), lastSequence)
+			}
+		}
+	}
+}
+
+func sum(values []int) int {
+	var v int
+	for _, value := range values {
+		v += value
+	}
+	return v
+}
@@ -0,0 +1,33 @@
+package db_benchmark
+
+// Key identifies a value by its content, in SSB this is the sha256 hash of a
+// message.
+type Key [32]byte
+
+// KeyIndexDatabaseSystem is implemented by database systems which in
+// addition to storing values by sequence can maintain an index mapping keys
+// to the sequences of the values.
+type KeyIndexDatabaseSystem interface {
+	DatabaseSystem
+	UpdateWithKeys(func(updater KeyIndexUpdater) error) error
+	ReadWithKeys(func(reader KeyIndexReader) error) error
+}
+
+type KeyIndexUpdater interface {
+	Updater
+
+	// AppendWithKey appends the value to the log and adds it to the index
+	// under the given key. The index is updated in the same transaction as
+	// the log, if the database system supports transactions. Deleting the
+	// value removes the key from the index.
+	AppendWithKey(key Key, value []byte) (Sequence, error)
+}
+
+type KeyIndexReader interface {
+	Reader
+
+	// GetSequence returns the sequence of the value which was appended
+	// with the given key. ErrNotFound is returned if the key isn't in the
+	// index or the value was deleted.
+	GetSequence(key Key) (Sequence, error)
+}
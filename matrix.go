@@ -0,0 +1,642 @@
+package db_benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/boreq/db_benchmark/workload"
+	"github.com/boreq/errors"
+)
+
+// Matrix describes which database systems, storage systems, data
+// constructors and workloads should be executed. Every combination of those
+// is benchmarked.
+type Matrix struct {
+	Systems   []MatrixSystem   `json:"systems"`
+	Storage   []MatrixStorage  `json:"storage"`
+	Data      []MatrixData     `json:"data"`
+	Workloads []MatrixWorkload `json:"workloads"`
+
+	// Seed is used to seed all sources of randomness. If it isn't set a
+	// seed is derived from the current time, see NewSeed.
+	Seed *int64 `json:"seed,omitempty"`
+
+	// Sweep is optional, if it is present the dataset size sweep is
+	// executed for every combination of systems, storage and data.
+	Sweep *MatrixSweep `json:"sweep,omitempty"`
+}
+
+// MatrixSystem is expanded into one database system per codec, transaction
+// size and durability. Durabilities default to DefaultDurability.
+type MatrixSystem struct {
+	Type             string            `json:"type"`
+	Name             string            `json:"name,omitempty"`
+	Codecs           []string          `json:"codecs,omitempty"`
+	TransactionSizes []int             `json:"transaction_sizes,omitempty"`
+	Durabilities     []string          `json:"durabilities,omitempty"`
+	Options          map[string]string `json:"options,omitempty"`
+}
+
+type MatrixStorage struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type MatrixData struct {
+	Name string `json:"name"`
+
+	// Path is the path to the file read by the dataset and ssb_export data
+	// constructors.
+	Path string `json:"path,omitempty"`
+
+	// Distribution, Size, MinSize, MaxSize, StdDev, Sigma, LargeSize,
+	// LargePercentage and Compressibility configure the sized_data data
+	// constructor, see SizedDataConfig. Distribution defaults to
+	// DefaultSizeDistribution and Size to DefaultValueSize.
+	Distribution    string  `json:"distribution,omitempty"`
+	Size            int     `json:"size,omitempty"`
+	MinSize         int     `json:"min_size,omitempty"`
+	MaxSize         int     `json:"max_size,omitempty"`
+	StdDev          int     `json:"stddev,omitempty"`
+	Sigma           float64 `json:"sigma,omitempty"`
+	LargeSize       int     `json:"large_size,omitempty"`
+	LargePercentage int     `json:"large_percentage,omitempty"`
+	Compressibility int     `json:"compressibility,omitempty"`
+}
+
+type MatrixWorkload struct {
+	Name string `json:"name"`
+
+	// Authors is the number of feeds used by the feed workloads, defaults
+	// to DefaultNumberOfAuthors.
+	Authors int `json:"authors,omitempty"`
+
+	// Percentage is the percentage of values removed by the delete and
+	// compact workloads, defaults to DefaultDeletePercentage.
+	Percentage int `json:"percentage,omitempty"`
+
+	// Readers, Writers and ReadPercentage configure the concurrent
+	// workload. They default to DefaultConcurrentReaders,
+	// DefaultConcurrentWriters and DefaultConcurrentReadPercentage if they
+	// aren't set. They are pointers so that zero can be configured e.g. to
+	// run a workload which only writes.
+	Readers        *int `json:"readers,omitempty"`
+	Writers        *int `json:"writers,omitempty"`
+	ReadPercentage *int `json:"read_percentage,omitempty"`
+
+	// Chooser selects the key chooser used by the read_random and
+	// read_iterate workloads, defaults to KeyChooserUniform. Theta
+	// configures the zipfian and latest key choosers and defaults to
+	// DefaultZipfianTheta. HotKeysPercentage and HotOperationsPercentage
+	// configure the hotspot key chooser and default to
+	// DefaultHotKeysPercentage and DefaultHotOperationsPercentage.
+	Chooser                 string  `json:"chooser,omitempty"`
+	Theta                   float64 `json:"theta,omitempty"`
+	HotKeysPercentage       int     `json:"hot_keys_percentage,omitempty"`
+	HotOperationsPercentage int     `json:"hot_operations_percentage,omitempty"`
+
+	// CoreWorkload selects one of the YCSB core workloads executed by the
+	// ycsb workload e.g. "a", see workload.CoreWorkloads. The key chooser
+	// defaults to the request distribution of the core workload but can be
+	// changed using Chooser and its parameters.
+	CoreWorkload string `json:"core_workload,omitempty"`
+
+	// Values is the number of values appended by every op of the append
+	// workload, defaults to DefaultAppendValues, or by the reopen workload
+	// before the database system is shut down, defaults to
+	// DefaultReopenValues. Unclean causes the reopen workload to kill the
+	// process appending the values instead of closing the database system.
+	Values  int  `json:"values,omitempty"`
+	Unclean bool `json:"unclean,omitempty"`
+
+	// ColdCache causes the database system to be reopened with its files
+	// evicted from the page cache before the measured operations, see
+	// Benchmark.ColdCache. It can be used with any workload and
+	// "_cold_cache" is appended to the name of the workload.
+	ColdCache bool `json:"cold_cache,omitempty"`
+}
+
+// MatrixSweep configures RunSweep.
+type MatrixSweep struct {
+	// Sizes are the numbers of values in the log at which throughput is
+	// measured, they must be positive and ascending.
+	Sizes []int `json:"sizes"`
+}
+
+// LoadMatrix reads a matrix from a JSON file.
+func LoadMatrix(path string) (Matrix, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Matrix{}, errors.Wrap(err, "error opening the file")
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+
+	var matrix Matrix
+	if err := decoder.Decode(&matrix); err != nil {
+		return Matrix{}, errors.Wrap(err, "error decoding the matrix")
+	}
+
+	return matrix, nil
+}
+
+// DefaultMatrix returns a matrix which contains all database systems, data
+// constructors and benchmarks and stores the data in the default temporary
+// directory.
+func DefaultMatrix() Matrix {
+	matrix := Matrix{
+		Systems: []MatrixSystem{
+			{
+				Type:         BoltDatabaseSystemType,
+				Codecs:       []string{CodecNone, CodecSnappy, CodecZSTD},
+				Durabilities: []string{DurabilityNone, DurabilityGroupCommit, DurabilityFsync},
+			},
+			{
+				Type:         BadgerDatabaseSystemType,
+				Codecs:       []string{CodecNone, CodecSnappy, CodecZSTD},
+				Durabilities: []string{DurabilityNone, DurabilityGroupCommit, DurabilityFsync},
+			},
+			{
+				Type:         MargaretDatabaseSystemType,
+				Codecs:       []string{CodecNone, CodecSnappy, CodecZSTD},
+				Durabilities: []string{DurabilityNone, DurabilityGroupCommit, DurabilityFsync},
+			},
+		},
+		Storage: []MatrixStorage{
+			{
+				Name: "default_storage",
+				Path: "",
+			},
+		},
+	}
+
+	for _, dataConstructor := range DataConstructors() {
+		matrix.Data = append(matrix.Data, MatrixData{Name: dataConstructor.Name})
+	}
+
+	for _, benchmark := range Benchmarks() {
+		matrix.Workloads = append(matrix.Workloads, MatrixWorkload{Name: benchmark.Name})
+	}
+
+	matrix.Workloads = append(matrix.Workloads,
+		MatrixWorkload{Name: FeedAppendWorkload},
+		MatrixWorkload{Name: FeedIterateWorkload},
+		MatrixWorkload{Name: DeleteWorkload},
+		MatrixWorkload{Name: CompactWorkload},
+		MatrixWorkload{Name: ConcurrentWorkload},
+		MatrixWorkload{Name: ReopenWorkload},
+		MatrixWorkload{Name: ReopenWorkload, Unclean: true},
+	)
+
+	return matrix
+}
+
+func (m Matrix) DatabaseSystems() ([]TestedDatabaseSystem, error) {
+	var v []TestedDatabaseSystem
+
+	for _, system := range m.Systems {
+		codecs := system.Codecs
+		if len(codecs) == 0 {
+			codecs = []string{CodecNone}
+		}
+
+		transactionSizes := system.TransactionSizes
+		if len(transactionSizes) == 0 {
+			transactionSizes = []int{DefaultTransactionSize}
+		}
+
+		durabilities := system.Durabilities
+		if len(durabilities) == 0 {
+			durabilities = []string{DefaultDurability}
+		}
+
+		for _, transactionSize := range transactionSizes {
+			for _, codec := range codecs {
+				for _, durability := range durabilities {
+					testedDatabaseSystem, err := NewTestedDatabaseSystem(DatabaseSystemConfig{
+						Type:            system.Type,
+						Name:            system.Name,
+						Codec:           codec,
+						TransactionSize: transactionSize,
+						Durability:      durability,
+						Options:         system.Options,
+					})
+					if err != nil {
+						return nil, errors.Wrapf(err, "error creating database system '%s'", system.Type)
+					}
+
+					v = append(v, testedDatabaseSystem)
+				}
+			}
+		}
+	}
+
+	if err := checkUniqueNames(len(v), func(i int) string { return v[i].Name }); err != nil {
+		return nil, errors.Wrap(err, "invalid database systems")
+	}
+
+	return v, nil
+}
+
+func (m Matrix) StorageSystems() ([]StorageSystem, error) {
+	var v []StorageSystem
+
+	for _, storage := range m.Storage {
+		if storage.Name == "" {
+			return nil, errors.New("storage name can't be empty")
+		}
+
+		v = append(v, StorageSystem{
+			Name: storage.Name,
+			Path: storage.Path,
+		})
+	}
+
+	if err := checkUniqueNames(len(v), func(i int) string { return v[i].Name }); err != nil {
+		return nil, errors.Wrap(err, "invalid storage systems")
+	}
+
+	return v, nil
+}
+
+func (m Matrix) DataConstructors() ([]DataConstructor, error) {
+	available := DataConstructors()
+
+	var v []DataConstructor
+
+	for _, data := range m.Data {
+		dataConstructor, err := newDataConstructor(available, data)
+		if err != nil {
+			CloseDataConstructors(v)
+			return nil, errors.Wrapf(err, "error creating data constructor '%s'", data.Name)
+		}
+
+		v = append(v, dataConstructor)
+	}
+
+	if err := checkUniqueNames(len(v), func(i int) string { return v[i].Name }); err != nil {
+		CloseDataConstructors(v)
+		return nil, errors.Wrap(err, "invalid data constructors")
+	}
+
+	return v, nil
+}
+
+func (m Matrix) Benchmarks() ([]Benchmark, error) {
+	var v []Benchmark
+
+	for _, workload := range m.Workloads {
+		benchmark, err := newBenchmark(workload)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error creating workload '%s'", workload.Name)
+		}
+
+		v = append(v, benchmark)
+	}
+
+	if err := checkUniqueNames(len(v), func(i int) string { return v[i].Name }); err != nil {
+		return nil, errors.Wrap(err, "invalid benchmarks")
+	}
+
+	return v, nil
+}
+
+// NewSeed returns a seed derived from the current time which is used when
+// the seed isn't specified.
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// SweepSizes returns the sizes of the sweep or nil if the matrix doesn't
+// define a sweep.
+func (m Matrix) SweepSizes() ([]int, error) {
+	if m.Sweep == nil {
+		return nil, nil
+	}
+
+	if len(m.Sweep.Sizes) == 0 {
+		return nil, errors.New("sweep doesn't define any sizes")
+	}
+
+	for i, size := range m.Sweep.Sizes {
+		if size <= 0 {
+			return nil, fmt.Errorf("sweep size must be positive, got %d", size)
+		}
+
+		if i > 0 && size <= m.Sweep.Sizes[i-1] {
+			return nil, errors.New("sweep sizes must be ascending")
+		}
+	}
+
+	return m.Sweep.Sizes, nil
+}
+
+func newBenchmark(workload MatrixWorkload) (Benchmark, error) {
+	// parameters which are left in unused weren't consumed by the workload
+	unused := workload
+	unused.Name = ""
+	unused.ColdCache = false
+
+	var benchmark Benchmark
+
+	switch workload.Name {
+	case AppendWorkload:
+		values := workload.Values
+		if values == 0 {
+			values = DefaultAppendValues
+		}
+		unused.Values = 0
+
+		if values < 0 {
+			return Benchmark{}, errors.New("number of values must be positive")
+		}
+
+		benchmark = NewAppendBenchmark(values)
+	case FeedAppendWorkload, FeedIterateWorkload:
+		authors := workload.Authors
+		if authors == 0 {
+			authors = DefaultNumberOfAuthors
+		}
+		unused.Authors = 0
+
+		if authors < 0 {
+			return Benchmark{}, errors.New("number of authors must be positive")
+		}
+
+		if workload.Name == FeedAppendWorkload {
+			benchmark = NewFeedAppendBenchmark(authors)
+		} else {
+			benchmark = NewFeedIterateBenchmark(authors)
+		}
+	case DeleteWorkload, CompactWorkload:
+		percentage := workload.Percentage
+		if percentage == 0 {
+			percentage = DefaultDeletePercentage
+		}
+		unused.Percentage = 0
+
+		if percentage < 0 || percentage > 100 {
+			return Benchmark{}, errors.New("percentage must be in range (0, 100]")
+		}
+
+		if workload.Name == DeleteWorkload {
+			benchmark = NewDeleteBenchmark(percentage)
+		} else {
+			benchmark = NewCompactBenchmark(percentage)
+		}
+	case ReadRandomWorkload, ReadIterateWorkload:
+		keyChooser, err := newKeyChooser(workload, &unused)
+		if err != nil {
+			return Benchmark{}, errors.Wrap(err, "error creating the key chooser")
+		}
+
+		if workload.Name == ReadRandomWorkload {
+			benchmark = NewReadRandomBenchmark(keyChooser)
+		} else {
+			benchmark = NewReadIterateBenchmark(keyChooser)
+		}
+	case YCSBWorkload:
+		v, err := newYCSBBenchmark(workload, &unused)
+		if err != nil {
+			return Benchmark{}, errors.Wrap(err, "error creating the ycsb benchmark")
+		}
+
+		benchmark = v
+	case ConcurrentWorkload:
+		readers := DefaultConcurrentReaders
+		if workload.Readers != nil {
+			readers = *workload.Readers
+		}
+		unused.Readers = nil
+
+		writers := DefaultConcurrentWriters
+		if workload.Writers != nil {
+			writers = *workload.Writers
+		}
+		unused.Writers = nil
+
+		readPercentage := DefaultConcurrentReadPercentage
+		if workload.ReadPercentage != nil {
+			readPercentage = *workload.ReadPercentage
+		}
+		unused.ReadPercentage = nil
+
+		if readers < 0 || writers < 0 {
+			return Benchmark{}, errors.New("number of readers and writers can't be negative")
+		}
+
+		if readPercentage < 0 || readPercentage > 100 {
+			return Benchmark{}, errors.New("read percentage must be in range [0, 100]")
+		}
+
+		if readPercentage > 0 && readers == 0 {
+			return Benchmark{}, errors.New("reads require at least one reader")
+		}
+
+		if readPercentage < 100 && writers == 0 {
+			return Benchmark{}, errors.New("writes require at least one writer")
+		}
+
+		benchmark = NewConcurrentBenchmark(readers, writers, readPercentage)
+	case ReopenWorkload:
+		values := workload.Values
+		if values == 0 {
+			values = DefaultReopenValues
+		}
+		unused.Values = 0
+		unused.Unclean = false
+
+		if values < 0 {
+			return Benchmark{}, errors.New("number of values must be positive")
+		}
+
+		benchmark = NewReopenBenchmark(values, workload.Unclean)
+	default:
+		v, ok := findBenchmark(Benchmarks(), workload.Name)
+		if !ok {
+			return Benchmark{}, errors.New("unknown workload")
+		}
+
+		benchmark = v
+	}
+
+	if unused != (MatrixWorkload{}) {
+		return Benchmark{}, errors.New("workload doesn't accept some of the parameters")
+	}
+
+	if workload.ColdCache {
+		benchmark.Name += "_cold_cache"
+		benchmark.ColdCache = true
+	}
+
+	return benchmark, nil
+}
+
+// newKeyChooser creates a key chooser and removes the parameters it
+// consumed from unused.
+func newKeyChooser(workload MatrixWorkload, unused *MatrixWorkload) (KeyChooser, error) {
+	unused.Chooser = ""
+
+	switch workload.Chooser {
+	case "", KeyChooserUniform:
+		return NewUniformKeyChooser(), nil
+	case KeyChooserZipfian, KeyChooserLatest:
+		theta := workload.Theta
+		if theta == 0 {
+			theta = DefaultZipfianTheta
+		}
+		unused.Theta = 0
+
+		if workload.Chooser == KeyChooserZipfian {
+			return NewZipfianKeyChooser(theta)
+		}
+		return NewLatestKeyChooser(theta)
+	case KeyChooserHotspot:
+		hotKeysPercentage := workload.HotKeysPercentage
+		if hotKeysPercentage == 0 {
+			hotKeysPercentage = DefaultHotKeysPercentage
+		}
+		unused.HotKeysPercentage = 0
+
+		hotOperationsPercentage := workload.HotOperationsPercentage
+		if hotOperationsPercentage == 0 {
+			hotOperationsPercentage = DefaultHotOperationsPercentage
+		}
+		unused.HotOperationsPercentage = 0
+
+		return NewHotspotKeyChooser(hotKeysPercentage, hotOperationsPercentage)
+	default:
+		return nil, fmt.Errorf("unknown key chooser '%s'", workload.Chooser)
+	}
+}
+
+// newYCSBBenchmark creates a benchmark executing a YCSB core workload and
+// removes the parameters it consumed from unused.
+func newYCSBBenchmark(w MatrixWorkload, unused *MatrixWorkload) (Benchmark, error) {
+	unused.CoreWorkload = ""
+
+	coreWorkload, err := workload.CoreWorkload(w.CoreWorkload)
+	if err != nil {
+		return Benchmark{}, errors.Wrap(err, "error getting the core workload")
+	}
+
+	var keyChooser KeyChooser
+	if w.Chooser == "" {
+		keyChooser, err = NewRequestDistributionKeyChooser(coreWorkload.RequestDistribution)
+	} else {
+		keyChooser, err = newKeyChooser(w, unused)
+	}
+	if err != nil {
+		return Benchmark{}, errors.Wrap(err, "error creating the key chooser")
+	}
+
+	return NewYCSBBenchmark(coreWorkload, keyChooser)
+}
+
+func newDataConstructor(available []DataConstructor, data MatrixData) (DataConstructor, error) {
+	// parameters which are left in unused weren't consumed by the data
+	// constructor
+	unused := data
+	unused.Name = ""
+
+	var construct func() (DataConstructor, error)
+
+	switch data.Name {
+	case DatasetData, SSBExportData:
+		if data.Path == "" {
+			return DataConstructor{}, errors.New("path must be set")
+		}
+		unused.Path = ""
+
+		if data.Name == DatasetData {
+			construct = func() (DataConstructor, error) {
+				return DatasetDataConstructor(data.Path)
+			}
+		} else {
+			construct = func() (DataConstructor, error) {
+				return SSBExportDataConstructor(data.Path)
+			}
+		}
+	case SizedData:
+		config := SizedDataConfig{
+			Distribution:    data.Distribution,
+			Size:            data.Size,
+			MinSize:         data.MinSize,
+			MaxSize:         data.MaxSize,
+			StdDev:          data.StdDev,
+			Sigma:           data.Sigma,
+			LargeSize:       data.LargeSize,
+			LargePercentage: data.LargePercentage,
+			Compressibility: data.Compressibility,
+		}
+		unused.Distribution = ""
+		unused.Size = 0
+		unused.MinSize = 0
+		unused.MaxSize = 0
+		unused.StdDev = 0
+		unused.Sigma = 0
+		unused.LargeSize = 0
+		unused.LargePercentage = 0
+		unused.Compressibility = 0
+
+		if config.Distribution == "" {
+			config.Distribution = DefaultSizeDistribution
+		}
+
+		if config.Size == 0 && config.Distribution != SizeDistributionUniform {
+			config.Size = DefaultValueSize
+		}
+
+		construct = func() (DataConstructor, error) {
+			return SizedDataConstructor(config)
+		}
+	default:
+		dataConstructor, ok := findDataConstructor(available, data.Name)
+		if !ok {
+			return DataConstructor{}, errors.New("unknown data constructor")
+		}
+
+		construct = func() (DataConstructor, error) {
+			return dataConstructor, nil
+		}
+	}
+
+	if unused != (MatrixData{}) {
+		return DataConstructor{}, errors.New("data constructor doesn't accept some of the parameters")
+	}
+
+	return construct()
+}
+
+func findDataConstructor(dataConstructors []DataConstructor, name string) (DataConstructor, bool) {
+	for _, dataConstructor := range dataConstructors {
+		if dataConstructor.Name == name {
+			return dataConstructor, true
+		}
+	}
+	return DataConstructor{}, false
+}
+
+func findBenchmark(benchmarks []Benchmark, name string) (Benchmark, bool) {
+	for _, benchmark := range benchmarks {
+		if benchmark.Name == name {
+			return benchmark, true
+		}
+	}
+	return Benchmark{}, false
+}
+
+func checkUniqueNames(n int, name func(i int) string) error {
+	names := make(map[string]struct{})
+	for i := 0; i < n; i++ {
+		if _, ok := names[name(i)]; ok {
+			return fmt.Errorf("duplicate name '%s'", name(i))
+		}
+		names[name(i)] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatrix(t *testing.T) {
+	matrix := loadMatrix(t)
+
+	_, err := matrix.DatabaseSystems()
+	require.NoError(t, err)
+
+	_, err = matrix.StorageSystems()
+	require.NoError(t, err)
+
+	_, err = matrix.DataConstructors()
+	require.NoError(t, err)
+
+	_, err = matrix.Benchmarks()
+	require.NoError(t, err)
+
+	_, err = matrix.SweepSizes()
+	require.NoError(t, err)
+}
+
+func TestSweepMatrix(t *testing.T) {
+	matrix, err := LoadMatrix("matrix_sweep.json")
+	require.NoError(t, err)
+
+	sizes, err := matrix.SweepSizes()
+	require.NoError(t, err)
+	require.NotEmpty(t, sizes)
+
+	_, err = matrix.DatabaseSystems()
+	require.NoError(t, err)
+
+	_, err = matrix.StorageSystems()
+	require.NoError(t, err)
+
+	_, err = matrix.DataConstructors()
+	require.NoError(t, err)
+}
+
+func TestTransactionSizesMatrix(t *testing.T) {
+	matrix, err := LoadMatrix("matrix_transaction_sizes.json")
+	require.NoError(t, err)
+
+	systems, err := matrix.DatabaseSystems()
+	require.NoError(t, err)
+	require.Len(t, systems, 18)
+
+	_, err = matrix.Benchmarks()
+	require.NoError(t, err)
+
+	_, err = Matrix{
+		Systems: []MatrixSystem{
+			{Type: MargaretDatabaseSystemType, TransactionSizes: []int{0}},
+		},
+	}.DatabaseSystems()
+	require.Error(t, err)
+}
+
+func TestMatrixData(t *testing.T) {
+	testCases := []struct {
+		Data          MatrixData
+		ExpectedName  string
+		ExpectedError bool
+	}{
+		{
+			Data:         MatrixData{Name: "random_data"},
+			ExpectedName: "random_data",
+		},
+		{
+			Data:          MatrixData{Name: "random_data", Size: 10},
+			ExpectedError: true,
+		},
+		{
+			Data:          MatrixData{Name: "random_data", Path: "some/path"},
+			ExpectedError: true,
+		},
+		{
+			Data:          MatrixData{Name: DatasetData},
+			ExpectedError: true,
+		},
+		{
+			Data:          MatrixData{Name: DatasetData, Path: "some/path", Size: 10},
+			ExpectedError: true,
+		},
+		{
+			Data:          MatrixData{Name: SizedData, Path: "some/path"},
+			ExpectedError: true,
+		},
+		{
+			Data:         MatrixData{Name: SizedData},
+			ExpectedName: "sized_data_fixed_1000_bytes_0_percent_compressible",
+		},
+		{
+			Data:         MatrixData{Name: SizedData, Size: 100, Compressibility: 50},
+			ExpectedName: "sized_data_fixed_100_bytes_50_percent_compressible",
+		},
+		{
+			Data:         MatrixData{Name: SizedData, Distribution: SizeDistributionUniform, MinSize: 10, MaxSize: 1000},
+			ExpectedName: "sized_data_uniform_10_to_1000_bytes_0_percent_compressible",
+		},
+		{
+			Data:          MatrixData{Name: SizedData, Distribution: SizeDistributionUniform, MinSize: 1000, MaxSize: 10},
+			ExpectedError: true,
+		},
+		{
+			Data:         MatrixData{Name: SizedData, Distribution: SizeDistributionNormal, StdDev: 200, Compressibility: 100},
+			ExpectedName: "sized_data_normal_1000_bytes_stddev_200_100_percent_compressible",
+		},
+		{
+			Data:          MatrixData{Name: SizedData, Distribution: SizeDistributionNormal},
+			ExpectedError: true,
+		},
+		{
+			Data:         MatrixData{Name: SizedData, Distribution: SizeDistributionLogNormal, Size: 500, Sigma: 1.5},
+			ExpectedName: "sized_data_lognormal_500_bytes_sigma_1.5_0_percent_compressible",
+		},
+		{
+			Data:         MatrixData{Name: SizedData, Distribution: SizeDistributionBimodal, Size: 200, LargeSize: 8000, LargePercentage: 10},
+			ExpectedName: "sized_data_bimodal_200_and_8000_bytes_10_percent_large_0_percent_compressible",
+		},
+		{
+			Data:          MatrixData{Name: SizedData, Distribution: SizeDistributionBimodal, Size: 200, LargeSize: 100, LargePercentage: 10},
+			ExpectedError: true,
+		},
+		{
+			Data:          MatrixData{Name: SizedData, Compressibility: 101},
+			ExpectedError: true,
+		},
+		{
+			Data:          MatrixData{Name: SizedData, Distribution: "unknown"},
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(fmt.Sprintf("%+v", testCase.Data), func(t *testing.T) {
+			dataConstructors, err := Matrix{Data: []MatrixData{testCase.Data}}.DataConstructors()
+			if testCase.ExpectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, dataConstructors, 1)
+			require.Equal(t, testCase.ExpectedName, dataConstructors[0].Name)
+		})
+	}
+}
+
+func TestMatrixSweepSizes(t *testing.T) {
+	testCases := []struct {
+		Sweep         *MatrixSweep
+		ExpectedSizes []int
+		ExpectedError bool
+	}{
+		{
+			Sweep:         nil,
+			ExpectedSizes: nil,
+		},
+		{
+			Sweep:         &MatrixSweep{Sizes: []int{10, 100, 1000}},
+			ExpectedSizes: []int{10, 100, 1000},
+		},
+		{
+			Sweep:         &MatrixSweep{},
+			ExpectedError: true,
+		},
+		{
+			Sweep:         &MatrixSweep{Sizes: []int{0, 100}},
+			ExpectedError: true,
+		},
+		{
+			Sweep:         &MatrixSweep{Sizes: []int{100, 10}},
+			ExpectedError: true,
+		},
+		{
+			Sweep:         &MatrixSweep{Sizes: []int{100, 100}},
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(fmt.Sprintf("%v", testCase.Sweep), func(t *testing.T) {
+			sizes, err := Matrix{Sweep: testCase.Sweep}.SweepSizes()
+			if testCase.ExpectedError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, testCase.ExpectedSizes, sizes)
+			}
+		})
+	}
+}
+
+func TestMatrixWorkloads(t *testing.T) {
+	testCases := []struct {
+		Workload      MatrixWorkload
+		ExpectedName  string
+		ExpectedError bool
+	}{
+		{
+			Workload:     MatrixWorkload{Name: "append"},
+			ExpectedName: "append",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReadRandomWorkload},
+			ExpectedName: "read_random",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReadRandomWorkload, Chooser: KeyChooserUniform},
+			ExpectedName: "read_random",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReadRandomWorkload, Chooser: KeyChooserZipfian},
+			ExpectedName: "read_random_zipfian_0.99",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReadIterateWorkload, Chooser: KeyChooserLatest, Theta: 0.5},
+			ExpectedName: "read_iterate_latest_0.5",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReadRandomWorkload, Chooser: KeyChooserHotspot},
+			ExpectedName: "read_random_hotspot_20_percent_keys_80_percent_operations",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReadIterateWorkload, Chooser: KeyChooserHotspot, HotKeysPercentage: 1, HotOperationsPercentage: 99},
+			ExpectedName: "read_iterate_hotspot_1_percent_keys_99_percent_operations",
+		},
+		{
+			Workload:      MatrixWorkload{Name: ReadRandomWorkload, Chooser: KeyChooserZipfian, Theta: 1},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: ReadRandomWorkload, Chooser: KeyChooserZipfian, HotKeysPercentage: 10},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: ReadRandomWorkload, Theta: 0.5},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: ReadRandomWorkload, Chooser: "unknown"},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: "read_sequential", Chooser: KeyChooserZipfian},
+			ExpectedError: true,
+		},
+		{
+			Workload:     MatrixWorkload{Name: YCSBWorkload, CoreWorkload: "a"},
+			ExpectedName: "ycsb_a_zipfian_0.99",
+		},
+		{
+			Workload:     MatrixWorkload{Name: YCSBWorkload, CoreWorkload: "d"},
+			ExpectedName: "ycsb_d_latest_0.99",
+		},
+		{
+			Workload:     MatrixWorkload{Name: YCSBWorkload, CoreWorkload: "e", Chooser: KeyChooserUniform},
+			ExpectedName: "ycsb_e_uniform",
+		},
+		{
+			Workload:      MatrixWorkload{Name: YCSBWorkload},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: YCSBWorkload, CoreWorkload: "g"},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: YCSBWorkload, CoreWorkload: "a", Theta: 0.5},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: ReadRandomWorkload, CoreWorkload: "a"},
+			ExpectedError: true,
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReadRandomWorkload, ColdCache: true},
+			ExpectedName: "read_random_cold_cache",
+		},
+		{
+			Workload:     MatrixWorkload{Name: YCSBWorkload, CoreWorkload: "c", ColdCache: true},
+			ExpectedName: "ycsb_c_zipfian_0.99_cold_cache",
+		},
+		{
+			Workload:     MatrixWorkload{Name: FeedAppendWorkload},
+			ExpectedName: "feed_append_100_authors",
+		},
+		{
+			Workload:     MatrixWorkload{Name: FeedIterateWorkload, Authors: 10},
+			ExpectedName: "feed_iterate_10_authors",
+		},
+		{
+			Workload:     MatrixWorkload{Name: DeleteWorkload, Percentage: 10},
+			ExpectedName: "delete_10_percent",
+		},
+		{
+			Workload:     MatrixWorkload{Name: CompactWorkload},
+			ExpectedName: "compact_50_percent",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ConcurrentWorkload},
+			ExpectedName: "concurrent_4_readers_1_writers_90_percent_reads",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ConcurrentWorkload, Readers: intPointer(8), Writers: intPointer(2), ReadPercentage: intPointer(50)},
+			ExpectedName: "concurrent_8_readers_2_writers_50_percent_reads",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ConcurrentWorkload, Readers: intPointer(0), ReadPercentage: intPointer(0)},
+			ExpectedName: "concurrent_0_readers_1_writers_0_percent_reads",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ConcurrentWorkload, Writers: intPointer(0), ReadPercentage: intPointer(100)},
+			ExpectedName: "concurrent_4_readers_0_writers_100_percent_reads",
+		},
+		{
+			Workload:      MatrixWorkload{Name: ConcurrentWorkload, ReadPercentage: intPointer(101)},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: ConcurrentWorkload, ReadPercentage: intPointer(-1)},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: ConcurrentWorkload, Readers: intPointer(0)},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: ConcurrentWorkload, Writers: intPointer(0)},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: DeleteWorkload, Percentage: 101},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: "append", Authors: 10},
+			ExpectedError: true,
+		},
+		{
+			Workload:     MatrixWorkload{Name: AppendWorkload, Values: 100000},
+			ExpectedName: "append",
+		},
+		{
+			Workload:      MatrixWorkload{Name: AppendWorkload, Values: -1},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: DeleteWorkload, Authors: 10},
+			ExpectedError: true,
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReopenWorkload},
+			ExpectedName: "reopen_100000_values",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReopenWorkload, Values: 1000, Unclean: true},
+			ExpectedName: "reopen_1000_values_unclean",
+		},
+		{
+			Workload:     MatrixWorkload{Name: ReopenWorkload, ColdCache: true},
+			ExpectedName: "reopen_100000_values_cold_cache",
+		},
+		{
+			Workload:      MatrixWorkload{Name: ReopenWorkload, Values: -1},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: ReadRandomWorkload, Unclean: true},
+			ExpectedError: true,
+		},
+		{
+			Workload:      MatrixWorkload{Name: "unknown"},
+			ExpectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Workload.Name, func(t *testing.T) {
+			benchmarks, err := Matrix{Workloads: []MatrixWorkload{testCase.Workload}}.Benchmarks()
+			if testCase.ExpectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, benchmarks, 1)
+			require.Equal(t, testCase.ExpectedName, benchmarks[0].Name)
+		})
+	}
+}
+
+func intPointer(v int) *int {
+	return &v
+}
+
+func TestDefaultMatrix(t *testing.T) {
+	systems, err := DefaultMatrix().DatabaseSystems()
+	require.NoError(t, err)
+
+	var names []string
+	for _, system := range systems {
+		names = append(names, system.Name)
+	}
+
+	require.Equal(t,
+		[]string{
+			"bbolt_no_sync_5000",
+			"bbolt_group_commit_5000",
+			"bbolt_fsync_5000",
+			"bbolt_snappy_no_sync_5000",
+			"bbolt_snappy_group_commit_5000",
+			"bbolt_snappy_fsync_5000",
+			"bbolt_zstd_no_sync_5000",
+			"bbolt_zstd_group_commit_5000",
+			"bbolt_zstd_fsync_5000",
+			"badger_no_sync_5000",
+			"badger_group_commit_5000",
+			"badger_fsync_5000",
+			"badger_snappy_no_sync_5000",
+			"badger_snappy_group_commit_5000",
+			"badger_snappy_fsync_5000",
+			"badger_zstd_no_sync_5000",
+			"badger_zstd_group_commit_5000",
+			"badger_zstd_fsync_5000",
+			"margaret_no_sync_5000",
+			"margaret_group_commit_5000",
+			"margaret_fsync_5000",
+			"margaret_snappy_no_sync_5000",
+			"margaret_snappy_group_commit_5000",
+			"margaret_snappy_fsync_5000",
+			"margaret_zstd_no_sync_5000",
+			"margaret_zstd_group_commit_5000",
+			"margaret_zstd_fsync_5000",
+		},
+		names,
+	)
+}
+
+func TestMatrixDurabilities(t *testing.T) {
+	systems, err := Matrix{
+		Systems: []MatrixSystem{
+			{Type: BoltDatabaseSystemType},
+			{Type: MargaretDatabaseSystemType, Durabilities: []string{DurabilityNone, DurabilityGroupCommit}},
+		},
+	}.DatabaseSystems()
+	require.NoError(t, err)
+
+	var names []string
+	for _, system := range systems {
+		names = append(names, system.Name)
+	}
+
+	require.Equal(t,
+		[]string{
+			"bbolt_fsync_5000",
+			"margaret_no_sync_5000",
+			"margaret_group_commit_5000",
+		},
+		names,
+	)
+
+	_, err = Matrix{
+		Systems: []MatrixSystem{
+			{Type: BadgerDatabaseSystemType, Durabilities: []string{"unknown"}},
+		},
+	}.DatabaseSystems()
+	require.Error(t, err)
+}
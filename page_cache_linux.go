@@ -0,0 +1,52 @@
+//go:build linux
+
+package db_benchmark
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/boreq/errors"
+	"golang.org/x/sys/unix"
+)
+
+// evictFromPageCache removes all files in the directory from the page cache
+// using posix_fadvise which doesn't require root privileges. Dirty pages
+// can't be evicted so the files are flushed first. Pages which are still
+// mapped by a process aren't evicted therefore the database system should be
+// closed.
+func evictFromPageCache(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if err := evictFileFromPageCache(path); err != nil {
+			return errors.Wrapf(err, "error evicting '%s'", path)
+		}
+
+		return nil
+	})
+}
+
+func evictFileFromPageCache(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "error opening the file")
+	}
+	defer f.Close()
+
+	if err := unix.Fdatasync(int(f.Fd())); err != nil {
+		return errors.Wrap(err, "error calling fdatasync")
+	}
+
+	if err := unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED); err != nil {
+		return errors.Wrap(err, "error calling fadvise")
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package db_benchmark
+
+import (
+	"github.com/boreq/errors"
+)
+
+func evictFromPageCache(dir string) error {
+	return errors.New("evicting files from the page cache is only supported on linux")
+}
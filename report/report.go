@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/boreq/errors"
 	"github.com/wcharczuk/go-chart/v2"
@@ -18,8 +21,14 @@ type BenchResults struct {
 	Goos               string
 	Goarch             string
 	Cpu                string
+	Seed               string
 	PerformanceResults []PerformanceBenchResult
 	SizeResults        []SizeBenchResult
+	SweepResults       []SweepBenchResult
+
+	// TransactionSizeResults contain the results of the append benchmark
+	// executed by systems with more than one transaction size.
+	TransactionSizeResults []TransactionSizeBenchResult
 }
 
 type PerformanceBenchResult struct {
@@ -32,9 +41,139 @@ type SizeBenchResult struct {
 	Systems       []SystemSizeBenchResult
 }
 
+// SweepBenchResult contains the results of the dataset size sweep
+// performed using a single storage system and data constructor.
+type SweepBenchResult struct {
+	BenchmarkName string
+	Systems       []SystemSweepBenchResult
+}
+
+// TransactionSizeBenchResult contains the results of a benchmark performed
+// using a single storage system and data constructor by systems which differ
+// only in the transaction size.
+type TransactionSizeBenchResult struct {
+	BenchmarkName string
+	Systems       []SystemTransactionSizeBenchResult
+}
+
+// SystemTransactionSizeBenchResult is named after the system without the
+// transaction size.
+type SystemTransactionSizeBenchResult struct {
+	SystemName string
+	Points     []TransactionSizePoint
+}
+
+type TransactionSizePoint struct {
+	TransactionSize int64
+	NsOp            float64
+}
+
+type SystemSweepBenchResult struct {
+	SystemName string
+	Points     []SweepPoint
+}
+
+// SweepPoint is a single measurement of an operation performed once the log
+// contained the given number of values.
+type SweepPoint struct {
+	Size      int64
+	Operation string
+	NsOp      float64
+}
+
+// Operations returns the sorted names of operations measured by the
+// system.
+func (r SystemSweepBenchResult) Operations() []string {
+	m := make(map[string]struct{})
+	for _, point := range r.Points {
+		m[point.Operation] = struct{}{}
+	}
+
+	var operations []string
+	for operation := range m {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	return operations
+}
+
 type SystemPerformanceBenchResult struct {
 	SystemName string
 	NsOp       float64
+
+	// Latencies contains percentiles of latencies of individual operations
+	// keyed by the name of the operation.
+	Latencies map[string]Latencies
+
+	// Metrics contains all other reported values keyed by their units.
+	Metrics map[string]float64
+}
+
+// Latencies contains a summary of latencies of an operation in nanoseconds.
+type Latencies struct {
+	P50  float64
+	P90  float64
+	P99  float64
+	P999 float64
+	Max  float64
+}
+
+// LatencyStatistics lists the names of the latency statistics in the order
+// in which they are charted.
+var LatencyStatistics = []string{"p50", "p90", "p99", "p99.9", "max"}
+
+// Get returns the value of the statistic with the given name.
+func (l Latencies) Get(statistic string) (float64, bool) {
+	switch statistic {
+	case "p50":
+		return l.P50, true
+	case "p90":
+		return l.P90, true
+	case "p99":
+		return l.P99, true
+	case "p99.9":
+		return l.P999, true
+	case "max":
+		return l.Max, true
+	default:
+		return 0, false
+	}
+}
+
+func (l *Latencies) set(statistic string, value float64) bool {
+	switch statistic {
+	case "p50":
+		l.P50 = value
+	case "p90":
+		l.P90 = value
+	case "p99":
+		l.P99 = value
+	case "p99.9":
+		l.P999 = value
+	case "max":
+		l.Max = value
+	default:
+		return false
+	}
+	return true
+}
+
+// Operations returns the sorted names of operations for which latencies
+// were reported by any of the systems.
+func (r PerformanceBenchResult) Operations() []string {
+	m := make(map[string]struct{})
+	for _, system := range r.Systems {
+		for operation := range system.Latencies {
+			m[operation] = struct{}{}
+		}
+	}
+
+	var operations []string
+	for operation := range m {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	return operations
 }
 
 type SystemSizeBenchResult struct {
@@ -74,8 +213,15 @@ func GetBenchResults(r io.Reader) (BenchResults, error) {
 		return BenchResults{}, errors.Wrap(err, "error getting size results")
 	}
 
+	sweepResults, err := getSweepBenchResults(bytes.NewReader(b))
+	if err != nil {
+		return BenchResults{}, errors.Wrap(err, "error getting sweep results")
+	}
+
 	result.PerformanceResults = performanceResults
 	result.SizeResults = sizeResults
+	result.SweepResults = sweepResults
+	result.TransactionSizeResults = getTransactionSizeBenchResults(performanceResults, transactionSizeWorkload)
 
 	return result, err
 }
@@ -98,6 +244,8 @@ func parseLine(line string, result *BenchResults) error {
 		result.Goarch = value
 	case "cpu":
 		result.Cpu = value
+	case "seed":
+		result.Seed = value
 	default:
 		return errors.New("unknown line")
 	}
@@ -108,36 +256,48 @@ func parseLine(line string, result *BenchResults) error {
 func getPerformanceBenchResults(r io.Reader) ([]PerformanceBenchResult, error) {
 	var results []PerformanceBenchResult
 
-	set, err := parse.ParseSet(r)
-	if err != nil {
-		return nil, errors.Wrap(err, "error parsing set")
-	}
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := scan.Text()
 
-	for _, benchmarks := range set {
-		for _, benchmark := range benchmarks {
-			if !strings.HasPrefix(benchmark.Name, "BenchmarkPerformance") {
-				continue
-			}
+		if !strings.HasPrefix(line, "BenchmarkPerformance") {
+			continue
+		}
 
-			systemName, benchmarkName, err := ParsePerformanceBenchmarkName(benchmark.Name)
-			if err != nil {
-				return nil, errors.Wrap(err, "error parsing benchmark name")
-			}
+		benchmark, err := parse.ParseLine(line)
+		if err != nil {
+			continue
+		}
 
-			bench, ok := findPerformanceBenchmark(results, benchmarkName)
-			if !ok {
-				results = append(results, PerformanceBenchResult{
-					BenchmarkName: benchmarkName,
-					Systems:       nil,
-				})
-				bench = &results[len(results)-1]
-			}
+		systemName, benchmarkName, err := ParsePerformanceBenchmarkName(benchmark.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing benchmark name")
+		}
 
-			bench.Systems = append(bench.Systems, SystemPerformanceBenchResult{
-				SystemName: systemName,
-				NsOp:       benchmark.NsPerOp,
+		latencies, metrics, err := parseMetrics(line)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing metrics")
+		}
+
+		bench, ok := findPerformanceBenchmark(results, benchmarkName)
+		if !ok {
+			results = append(results, PerformanceBenchResult{
+				BenchmarkName: benchmarkName,
+				Systems:       nil,
 			})
+			bench = &results[len(results)-1]
 		}
+
+		bench.Systems = append(bench.Systems, SystemPerformanceBenchResult{
+			SystemName: systemName,
+			NsOp:       benchmark.NsPerOp,
+			Latencies:  latencies,
+			Metrics:    metrics,
+		})
+	}
+
+	if err := scan.Err(); err != nil {
+		return nil, errors.Wrap(err, "scan error")
 	}
 
 	sort.Slice(results, func(i, j int) bool {
@@ -153,6 +313,57 @@ func getPerformanceBenchResults(r io.Reader) ([]PerformanceBenchResult, error) {
 	return results, nil
 }
 
+const latencyUnitSuffix = "_ns"
+
+// parseMetrics parses the values reported in a benchmark line other than
+// ns/op. Latencies are reported using units such as "get_p99.9_ns".
+func parseMetrics(line string) (map[string]Latencies, map[string]float64, error) {
+	latencies := make(map[string]Latencies)
+	metrics := make(map[string]float64)
+
+	fields := strings.Fields(line)
+	for i := 2; i+1 < len(fields); i += 2 {
+		value := fields[i]
+		unit := fields[i+1]
+
+		if unit == "ns/op" {
+			continue
+		}
+
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error parsing value of '%s'", unit)
+		}
+
+		if operation, statistic, ok := parseLatencyUnit(unit); ok {
+			l := latencies[operation]
+			if l.set(statistic, f) {
+				latencies[operation] = l
+				continue
+			}
+		}
+
+		metrics[unit] = f
+	}
+
+	return latencies, metrics, nil
+}
+
+func parseLatencyUnit(unit string) (string, string, bool) {
+	if !strings.HasSuffix(unit, latencyUnitSuffix) {
+		return "", "", false
+	}
+
+	unit = strings.TrimSuffix(unit, latencyUnitSuffix)
+
+	i := strings.LastIndex(unit, "_")
+	if i < 0 {
+		return "", "", false
+	}
+
+	return unit[:i], unit[i+1:], true
+}
+
 func getSizeBenchResults(r io.Reader) ([]SizeBenchResult, error) {
 	var results []SizeBenchResult
 
@@ -224,6 +435,151 @@ func getSizeBenchResults(r io.Reader) ([]SizeBenchResult, error) {
 	return results, nil
 }
 
+func getSweepBenchResults(r io.Reader) ([]SweepBenchResult, error) {
+	var results []SweepBenchResult
+
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := scan.Text()
+
+		if !strings.HasPrefix(line, "BenchmarkSweep") {
+			continue
+		}
+
+		benchmark, err := parse.ParseLine(line)
+		if err != nil {
+			continue
+		}
+
+		systemName, benchmarkName, size, operation, err := ParseSweepBenchmarkName(benchmark.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing benchmark name")
+		}
+
+		bench, ok := findSweepBenchmark(results, benchmarkName)
+		if !ok {
+			results = append(results, SweepBenchResult{
+				BenchmarkName: benchmarkName,
+				Systems:       nil,
+			})
+			bench = &results[len(results)-1]
+		}
+
+		system, ok := findSweepSystem(bench.Systems, systemName)
+		if !ok {
+			bench.Systems = append(bench.Systems, SystemSweepBenchResult{
+				SystemName: systemName,
+			})
+			system = &bench.Systems[len(bench.Systems)-1]
+		}
+
+		system.Points = append(system.Points, SweepPoint{
+			Size:      size,
+			Operation: operation,
+			NsOp:      benchmark.NsPerOp,
+		})
+	}
+
+	if err := scan.Err(); err != nil {
+		return nil, errors.Wrap(err, "scan error")
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].BenchmarkName < results[j].BenchmarkName
+	})
+
+	for _, result := range results {
+		sort.Slice(result.Systems, func(i, j int) bool {
+			return result.Systems[i].SystemName < result.Systems[j].SystemName
+		})
+
+		for _, system := range result.Systems {
+			sort.SliceStable(system.Points, func(i, j int) bool {
+				return system.Points[i].Size < system.Points[j].Size
+			})
+		}
+	}
+
+	return results, nil
+}
+
+const transactionSizeWorkload = "append"
+
+// getTransactionSizeBenchResults groups the results of the given workload by
+// the names of the systems without the transaction size. Only systems which
+// were benchmarked with more than one transaction size are returned.
+func getTransactionSizeBenchResults(performanceResults []PerformanceBenchResult, workload string) []TransactionSizeBenchResult {
+	var results []TransactionSizeBenchResult
+
+	for _, performanceResult := range performanceResults {
+		prefix, benchmarkWorkload := path.Split(performanceResult.BenchmarkName)
+		if trimProcs(benchmarkWorkload) != workload {
+			continue
+		}
+
+		benchmarkName := prefix + workload
+
+		for _, system := range performanceResult.Systems {
+			systemName, transactionSize, ok := ParseTransactionSize(system.SystemName)
+			if !ok {
+				continue
+			}
+
+			bench, ok := findTransactionSizeBenchmark(results, benchmarkName)
+			if !ok {
+				results = append(results, TransactionSizeBenchResult{
+					BenchmarkName: benchmarkName,
+				})
+				bench = &results[len(results)-1]
+			}
+
+			benchSystem, ok := findTransactionSizeSystem(bench.Systems, systemName)
+			if !ok {
+				bench.Systems = append(bench.Systems, SystemTransactionSizeBenchResult{
+					SystemName: systemName,
+				})
+				benchSystem = &bench.Systems[len(bench.Systems)-1]
+			}
+
+			benchSystem.Points = append(benchSystem.Points, TransactionSizePoint{
+				TransactionSize: transactionSize,
+				NsOp:            system.NsOp,
+			})
+		}
+	}
+
+	var filtered []TransactionSizeBenchResult
+	for _, result := range results {
+		var systems []SystemTransactionSizeBenchResult
+		for _, system := range result.Systems {
+			if len(system.Points) < 2 {
+				continue
+			}
+
+			sort.Slice(system.Points, func(i, j int) bool {
+				return system.Points[i].TransactionSize < system.Points[j].TransactionSize
+			})
+			systems = append(systems, system)
+		}
+
+		if len(systems) == 0 {
+			continue
+		}
+
+		sort.Slice(systems, func(i, j int) bool {
+			return systems[i].SystemName < systems[j].SystemName
+		})
+		result.Systems = systems
+		filtered = append(filtered, result)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].BenchmarkName < filtered[j].BenchmarkName
+	})
+
+	return filtered
+}
+
 const (
 	chartWidth    = 2000
 	chartBarWidth = 300
@@ -263,6 +619,278 @@ func MakePerformanceResultChart(result PerformanceBenchResult) (chart.BarChart,
 	return graph, nil
 }
 
+// MakeLatencyResultChart creates a chart showing the latency percentiles of
+// the given operation with a separate series for each system.
+func MakeLatencyResultChart(result PerformanceBenchResult, operation string) (chart.Chart, error) {
+	var ticks []chart.Tick
+	for i, statistic := range LatencyStatistics {
+		ticks = append(ticks, chart.Tick{Value: float64(i), Label: statistic})
+	}
+
+	graph := chart.Chart{
+		Title: fmt.Sprintf("%s (%s)", result.BenchmarkName, operation),
+		Background: chart.Style{
+			Padding: chart.Box{
+				Top:  40,
+				Left: 200,
+			},
+		},
+		Height: 512,
+		Width:  chartWidth,
+		XAxis: chart.XAxis{
+			Name:  "percentile",
+			Ticks: ticks,
+		},
+		// go-chart doesn't support logarithmic axes so logarithms of the
+		// values are plotted instead as the maximum latencies are often
+		// orders of magnitude larger than the median
+		YAxis: chart.YAxis{
+			Name:           "latency (log scale)",
+			ValueFormatter: formatLogNanoseconds,
+		},
+	}
+
+	for _, system := range result.Systems {
+		latencies, ok := system.Latencies[operation]
+		if !ok {
+			continue
+		}
+
+		series := chart.ContinuousSeries{
+			Name: system.SystemName,
+		}
+
+		for i, statistic := range LatencyStatistics {
+			v, ok := latencies.Get(statistic)
+			if !ok {
+				return chart.Chart{}, fmt.Errorf("unknown statistic '%s'", statistic)
+			}
+
+			if v < 1 {
+				v = 1
+			}
+
+			series.XValues = append(series.XValues, float64(i))
+			series.YValues = append(series.YValues, math.Log10(v))
+		}
+
+		graph.Series = append(graph.Series, series)
+	}
+
+	if len(graph.Series) == 0 {
+		return chart.Chart{}, errors.New("no systems reported latencies of this operation")
+	}
+
+	graph.Elements = []chart.Renderable{
+		chart.LegendLeft(&graph),
+	}
+
+	return graph, nil
+}
+
+// formatLogNanoseconds formats a base 10 logarithm of a number of
+// nanoseconds as a duration rounded to three significant digits.
+func formatLogNanoseconds(v interface{}) string {
+	f, ok := v.(float64)
+	if !ok {
+		return ""
+	}
+
+	ns := math.Pow(10, f)
+	precision := math.Pow(10, math.Floor(math.Log10(ns))-2)
+	if precision < 1 {
+		precision = 1
+	}
+
+	return time.Duration(ns).Round(time.Duration(precision)).String()
+}
+
+// MakeSweepResultChart creates a chart showing the throughput of each
+// operation measured by the system as a function of the size of the log.
+func MakeSweepResultChart(result SweepBenchResult, system SystemSweepBenchResult) (chart.Chart, error) {
+	var ticks []chart.Tick
+	for _, point := range system.Points {
+		if point.Size <= 0 {
+			continue
+		}
+
+		if len(ticks) > 0 && ticks[len(ticks)-1].Label == formatCount(float64(point.Size)) {
+			continue
+		}
+
+		ticks = append(ticks, chart.Tick{
+			Value: math.Log10(float64(point.Size)),
+			Label: formatCount(float64(point.Size)),
+		})
+	}
+
+	graph := chart.Chart{
+		Title: fmt.Sprintf("%s (%s)", result.BenchmarkName, system.SystemName),
+		Background: chart.Style{
+			Padding: chart.Box{
+				Top:  40,
+				Left: 200,
+			},
+		},
+		Height: 512,
+		Width:  chartWidth,
+		// sizes usually grow exponentially so their logarithms are
+		// plotted instead
+		XAxis: chart.XAxis{
+			Name:  "number of values in the log (log scale)",
+			Ticks: ticks,
+		},
+		YAxis: chart.YAxis{
+			Name:           "operations per second",
+			ValueFormatter: formatCountValue,
+			Range: &chart.ContinuousRange{
+				Min: 0,
+				Max: 0,
+			},
+		},
+	}
+
+	for _, operation := range system.Operations() {
+		series := chart.ContinuousSeries{
+			Name: operation,
+		}
+
+		for _, point := range system.Points {
+			if point.Operation != operation || point.Size <= 0 || point.NsOp <= 0 {
+				continue
+			}
+
+			opsPerSecond := float64(time.Second) / point.NsOp
+
+			series.XValues = append(series.XValues, math.Log10(float64(point.Size)))
+			series.YValues = append(series.YValues, opsPerSecond)
+
+			if v := opsPerSecond * 1.1; v > graph.YAxis.Range.GetMax() {
+				graph.YAxis.Range.SetMax(v)
+			}
+		}
+
+		if len(series.XValues) == 0 {
+			continue
+		}
+
+		graph.Series = append(graph.Series, series)
+	}
+
+	if len(graph.Series) == 0 {
+		return chart.Chart{}, errors.New("system didn't report any measurements")
+	}
+
+	graph.Elements = []chart.Renderable{
+		chart.LegendLeft(&graph),
+	}
+
+	return graph, nil
+}
+
+// MakeTransactionSizeResultChart creates a chart showing ns per op as a
+// function of the transaction size with a separate series for each system.
+func MakeTransactionSizeResultChart(result TransactionSizeBenchResult) (chart.Chart, error) {
+	var ticks []chart.Tick
+	seen := make(map[int64]bool)
+	for _, system := range result.Systems {
+		for _, point := range system.Points {
+			if point.TransactionSize <= 0 || seen[point.TransactionSize] {
+				continue
+			}
+			seen[point.TransactionSize] = true
+
+			ticks = append(ticks, chart.Tick{
+				Value: math.Log10(float64(point.TransactionSize)),
+				Label: formatCount(float64(point.TransactionSize)),
+			})
+		}
+	}
+
+	sort.Slice(ticks, func(i, j int) bool {
+		return ticks[i].Value < ticks[j].Value
+	})
+
+	graph := chart.Chart{
+		Title: result.BenchmarkName,
+		Background: chart.Style{
+			Padding: chart.Box{
+				Top:  40,
+				Left: 200,
+			},
+		},
+		Height: 512,
+		Width:  chartWidth,
+		// transaction sizes and the resulting times usually differ by
+		// orders of magnitude so their logarithms are plotted instead
+		XAxis: chart.XAxis{
+			Name:  "transaction size (log scale)",
+			Ticks: ticks,
+		},
+		YAxis: chart.YAxis{
+			Name:           "time per op (log scale)",
+			ValueFormatter: formatLogNanoseconds,
+		},
+	}
+
+	for _, system := range result.Systems {
+		series := chart.ContinuousSeries{
+			Name: system.SystemName,
+		}
+
+		for _, point := range system.Points {
+			if point.TransactionSize <= 0 || point.NsOp <= 0 {
+				continue
+			}
+
+			series.XValues = append(series.XValues, math.Log10(float64(point.TransactionSize)))
+			series.YValues = append(series.YValues, math.Log10(point.NsOp))
+		}
+
+		if len(series.XValues) == 0 {
+			continue
+		}
+
+		graph.Series = append(graph.Series, series)
+	}
+
+	if len(graph.Series) == 0 {
+		return chart.Chart{}, errors.New("no systems reported measurements")
+	}
+
+	graph.Elements = []chart.Renderable{
+		chart.LegendLeft(&graph),
+	}
+
+	return graph, nil
+}
+
+func formatCountValue(v interface{}) string {
+	f, ok := v.(float64)
+	if !ok {
+		return ""
+	}
+
+	return formatCount(f)
+}
+
+// formatCount formats a count using metric prefixes e.g. 1k or 10M.
+func formatCount(v float64) string {
+	for _, prefix := range []struct {
+		Value  float64
+		Symbol string
+	}{
+		{1e9, "G"},
+		{1e6, "M"},
+		{1e3, "k"},
+	} {
+		if v >= prefix.Value {
+			return strconv.FormatFloat(v/prefix.Value, 'g', 3, 64) + prefix.Symbol
+		}
+	}
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
 func MakeSizeResultChart(result SizeBenchResult) (chart.BarChart, error) {
 	graph := chart.BarChart{
 		Title: result.BenchmarkName,
@@ -315,6 +943,56 @@ func ParseSizeBenchmarkName(name string) (string, string, error) {
 	return split[1], split[2], nil
 }
 
+// ParseSweepBenchmarkName parses names such as
+// "BenchmarkSweep/system/storage/data/size_1000/append-8" returning the name
+// of the system, the name of the benchmark consisting of the storage and
+// data, the size of the log and the operation.
+func ParseSweepBenchmarkName(name string) (string, string, int64, string, error) {
+	split := strings.Split(name, "/")
+	if len(split) != 6 {
+		return "", "", 0, "", errors.New("invalid name")
+	}
+
+	sizeString := strings.TrimPrefix(split[4], "size_")
+	if sizeString == split[4] {
+		return "", "", 0, "", errors.New("invalid size")
+	}
+
+	size, err := strconv.ParseInt(sizeString, 10, 64)
+	if err != nil {
+		return "", "", 0, "", errors.Wrap(err, "error parsing size")
+	}
+
+	return split[1], split[2] + "/" + split[3], size, trimProcs(split[5]), nil
+}
+
+// ParseTransactionSize splits names of systems such as "badger_fsync_5000"
+// into the name without the transaction size and the transaction size.
+func ParseTransactionSize(systemName string) (string, int64, bool) {
+	i := strings.LastIndex(systemName, "_")
+	if i < 0 {
+		return "", 0, false
+	}
+
+	transactionSize, err := strconv.ParseInt(systemName[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return systemName[:i], transactionSize, true
+}
+
+// trimProcs removes the GOMAXPROCS suffix e.g. "-8" from the name of a
+// benchmark.
+func trimProcs(name string) string {
+	if i := strings.LastIndex(name, "-"); i >= 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			return name[:i]
+		}
+	}
+	return name
+}
+
 func findPerformanceBenchmark(results []PerformanceBenchResult, benchmarkName string) (*PerformanceBenchResult, bool) {
 	for i := range results {
 		if results[i].BenchmarkName == benchmarkName {
@@ -332,3 +1010,39 @@ func findSizeBenchmark(results []SizeBenchResult, benchmarkName string) (*SizeBe
 	}
 	return nil, false
 }
+
+func findSweepBenchmark(results []SweepBenchResult, benchmarkName string) (*SweepBenchResult, bool) {
+	for i := range results {
+		if results[i].BenchmarkName == benchmarkName {
+			return &results[i], true
+		}
+	}
+	return nil, false
+}
+
+func findTransactionSizeBenchmark(results []TransactionSizeBenchResult, benchmarkName string) (*TransactionSizeBenchResult, bool) {
+	for i := range results {
+		if results[i].BenchmarkName == benchmarkName {
+			return &results[i], true
+		}
+	}
+	return nil, false
+}
+
+func findTransactionSizeSystem(systems []SystemTransactionSizeBenchResult, systemName string) (*SystemTransactionSizeBenchResult, bool) {
+	for i := range systems {
+		if systems[i].SystemName == systemName {
+			return &systems[i], true
+		}
+	}
+	return nil, false
+}
+
+func findSweepSystem(systems []SystemSweepBenchResult, systemName string) (*SystemSweepBenchResult, bool) {
+	for i := range systems {
+		if systems[i].SystemName == systemName {
+			return &systems[i], true
+		}
+	}
+	return nil, false
+}
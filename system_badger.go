@@ -1,19 +1,36 @@
 package db_benchmark
 
 import (
+	"bytes"
+	"runtime"
+	"sync"
+
 	"github.com/boreq/errors"
 	"github.com/dgraph-io/badger/v4"
 )
 
 type BadgerDatabaseSystem struct {
 	preferredTransactionSize int
-	db                       *badger.DB
+	options                  badger.Options
+	groupCommitter           *groupCommitter
+
+	// mutex guards db which is replaced by Compact.
+	mutex sync.RWMutex
+	db    *badger.DB
 }
 
-func NewBadgerDatabaseSystem(dir string, fn func(*badger.Options), preferredTransactionSize int) (*BadgerDatabaseSystem, error) {
+// NewBadgerDatabaseSystem maps DurabilityFsync onto SyncWrites. With
+// DurabilityGroupCommit SyncWrites is disabled and the database is synced
+// after transactions are committed, concurrent transactions share a sync.
+func NewBadgerDatabaseSystem(dir string, fn func(*badger.Options), preferredTransactionSize int, durability string) (*BadgerDatabaseSystem, error) {
+	if err := checkDurability(durability); err != nil {
+		return nil, errors.Wrap(err, "invalid durability")
+	}
+
 	opt := badger.
 		DefaultOptions(dir).
-		WithLoggingLevel(badger.ERROR)
+		WithLoggingLevel(badger.ERROR).
+		WithSyncWrites(durability == DurabilityFsync)
 
 	if fn != nil {
 		fn(&opt)
@@ -24,15 +41,31 @@ func NewBadgerDatabaseSystem(dir string, fn func(*badger.Options), preferredTran
 		return nil, errors.Wrap(err, "error opening the database")
 	}
 
-	return &BadgerDatabaseSystem{db: db, preferredTransactionSize: preferredTransactionSize}, nil
+	s := &BadgerDatabaseSystem{db: db, options: opt, preferredTransactionSize: preferredTransactionSize}
+	if durability == DurabilityGroupCommit {
+		// the database is replaced by Compact, the sync is called by update
+		// which holds the read lock
+		s.groupCommitter = newGroupCommitter(func() error {
+			return s.db.Sync()
+		})
+	}
+
+	return s, nil
 }
 
 func (b *BadgerDatabaseSystem) PreferredTransactionSize() int {
 	return b.preferredTransactionSize
 }
 
+// Update splits the transaction if it grows too big, see update.
 func (b *BadgerDatabaseSystem) Update(fn func(updater Updater) error) error {
-	return b.db.Update(func(tx *badger.Txn) error {
+	return b.update(func(updater *TxBadgerDatabaseSystem) error {
+		return fn(updater)
+	})
+}
+
+func (b *BadgerDatabaseSystem) Read(fn func(reader Reader) error) error {
+	return b.view(func(tx *badger.Txn) error {
 		updater, err := NewTxBadgerDatabaseSystem(tx)
 		if err != nil {
 			return errors.Wrap(err, "error creating a tx database system")
@@ -42,48 +75,294 @@ func (b *BadgerDatabaseSystem) Update(fn func(updater Updater) error) error {
 	})
 }
 
-func (b *BadgerDatabaseSystem) Read(fn func(reader Reader) error) error {
-	return b.db.View(func(tx *badger.Txn) error {
-		updater, err := NewTxBadgerDatabaseSystem(tx)
+func (b *BadgerDatabaseSystem) UpdateFeeds(fn func(updater FeedUpdater) error) error {
+	return b.update(func(updater *TxBadgerDatabaseSystem) error {
+		return fn(updater)
+	})
+}
+
+func (b *BadgerDatabaseSystem) ReadFeeds(fn func(reader FeedReader) error) error {
+	return b.view(func(tx *badger.Txn) error {
+		reader, err := NewTxBadgerDatabaseSystem(tx)
 		if err != nil {
 			return errors.Wrap(err, "error creating a tx database system")
 		}
 
+		return fn(reader)
+	})
+}
+
+func (b *BadgerDatabaseSystem) UpdateWithKeys(fn func(updater KeyIndexUpdater) error) error {
+	return b.update(func(updater *TxBadgerDatabaseSystem) error {
 		return fn(updater)
 	})
 }
 
+func (b *BadgerDatabaseSystem) ReadWithKeys(fn func(reader KeyIndexReader) error) error {
+	return b.view(func(tx *badger.Txn) error {
+		reader, err := NewTxBadgerDatabaseSystem(tx)
+		if err != nil {
+			return errors.Wrap(err, "error creating a tx database system")
+		}
+
+		return fn(reader)
+	})
+}
+
+// Compact reopens the database so that the memtables are flushed, flattens
+// the LSM tree so that the tombstones are dropped and then garbage collects
+// the value log until there is nothing left to rewrite. If reopening fails
+// the database system remains closed.
+func (b *BadgerDatabaseSystem) Compact() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// closing the database flushes the memtables, the tables created from
+	// them are only compacted if CompactL0OnClose is set
+	for _, options := range []badger.Options{b.options.WithCompactL0OnClose(true), b.options} {
+		if err := b.db.Close(); err != nil {
+			return errors.Wrap(err, "error closing the database")
+		}
+
+		db, err := badger.Open(options)
+		if err != nil {
+			return errors.Wrap(err, "error reopening the database")
+		}
+
+		b.db = db
+	}
+
+	if err := b.db.Flatten(runtime.NumCPU()); err != nil {
+		return errors.Wrap(err, "error calling flatten")
+	}
+
+	for {
+		if err := b.db.RunValueLogGC(badgerValueLogGCDiscardRatio); err != nil {
+			if errors.Is(err, badger.ErrNoRewrite) {
+				return nil
+			}
+			return errors.Wrap(err, "error calling run value log gc")
+		}
+	}
+}
+
 func (b *BadgerDatabaseSystem) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
 	return b.db.Close()
 }
 
 func (b *BadgerDatabaseSystem) Sync() error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
 	return b.db.Sync()
 }
 
+func (b *BadgerDatabaseSystem) view(fn func(tx *badger.Txn) error) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.db.View(fn)
+}
+
+// update executes fn in a new transaction. Badger limits the size of a
+// transaction so once an operation wouldn't fit in it the transaction is
+// committed and fn continues in a new transaction, see
+// TxBadgerDatabaseSystem.reserve. This means that large updates aren't
+// atomic.
+func (b *BadgerDatabaseSystem) update(fn func(updater *TxBadgerDatabaseSystem) error) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	updater := newSplittableTxBadgerDatabaseSystem(b.db)
+	defer func() {
+		updater.tx.Discard()
+	}()
+
+	if err := fn(updater); err != nil {
+		return err
+	}
+
+	if err := updater.commit(); err != nil {
+		return err
+	}
+
+	return b.committed()
+}
+
+// committed waits for the committed transaction to be synced if group commit
+// is used, otherwise SyncWrites decides if it was synced.
+func (b *BadgerDatabaseSystem) committed() error {
+	if b.groupCommitter == nil {
+		return nil
+	}
+
+	if err := b.groupCommitter.Sync(); err != nil {
+		return errors.Wrap(err, "error syncing the committed transaction")
+	}
+
+	return nil
+}
+
+const badgerValueLogGCDiscardRatio = 0.5
+
 var badgerValuePrefix = []byte("value")
 var badgerLastSequenceKey = []byte("last_sequence")
+var badgerFeedValuePrefix = []byte("feed_value")
+var badgerFeedLastSequencePrefix = []byte("feed_last_sequence")
+var badgerKeyIndexPrefix = []byte("key_index")
+var badgerKeyIndexSequencePrefix = []byte("sequence_key")
+
+// badgerTxnOverhead is larger than the size which badger attributes to an
+// empty transaction.
+const badgerTxnOverhead = 64
 
 type TxBadgerDatabaseSystem struct {
 	tx *badger.Txn
+
+	// db is used to split the transaction, it is nil if the transaction
+	// can't be split.
+	db *badger.DB
+
+	// count and size estimate the number of entries and the size of the
+	// transaction in the same way as badger does but never underestimate
+	// it, see reserve.
+	count int64
+	size  int64
+
+	// partiallyCommitted is set once a part of the update was committed.
+	partiallyCommitted bool
 }
 
 func NewTxBadgerDatabaseSystem(tx *badger.Txn) (*TxBadgerDatabaseSystem, error) {
 	return &TxBadgerDatabaseSystem{tx: tx}, nil
 }
 
-func (t *TxBadgerDatabaseSystem) Append(value []byte) error {
+func newSplittableTxBadgerDatabaseSystem(db *badger.DB) *TxBadgerDatabaseSystem {
+	return &TxBadgerDatabaseSystem{
+		tx:    db.NewTransaction(true),
+		db:    db,
+		count: 1,
+		size:  badgerTxnOverhead,
+	}
+}
+
+// Append reserves space before reading the last sequence so that the last
+// sequence is read in the same transaction in which the value is written.
+// Sequences are encoded using a fixed number of bytes so the size of the
+// entries doesn't depend on the sequence.
+func (t *TxBadgerDatabaseSystem) Append(value []byte) (Sequence, error) {
+	if err := t.reserve(
+		badgerWrite{t.valueKey(0), value},
+		badgerWrite{badgerLastSequenceKey, marshalSequence(0)},
+	); err != nil {
+		return 0, errors.Wrap(err, "error reserving space in the transaction")
+	}
+
 	seq, err := t.getNextSequence()
 	if err != nil {
-		return errors.Wrap(err, "error calling get next sequence")
+		return 0, errors.Wrap(err, "error calling get next sequence")
 	}
 
-	if err := t.tx.Set(t.valueKey(seq), value); err != nil {
-		return errors.Wrap(err, "error calling set")
+	return t.append(seq, value)
+}
+
+func (t *TxBadgerDatabaseSystem) append(seq Sequence, value []byte) (Sequence, error) {
+	if err := t.set(t.valueKey(seq), value); err != nil {
+		return 0, errors.Wrap(err, "error calling set")
 	}
 
 	if err := t.setLastSequence(seq); err != nil {
-		return errors.Wrap(err, "error calling set last sequence")
+		return 0, errors.Wrap(err, "error calling set last sequence")
+	}
+
+	return seq, nil
+}
+
+// Delete writes a tombstone for the value, the space is reclaimed only when
+// badger compacts the tables and collects the value log.
+func (t *TxBadgerDatabaseSystem) Delete(seq Sequence) error {
+	if err := t.reserve(
+		badgerWrite{key: t.valueKey(seq)},
+		badgerWrite{key: t.keyIndexKey(Key{})},
+		badgerWrite{key: t.keyIndexSequenceKey(seq)},
+	); err != nil {
+		return errors.Wrap(err, "error reserving space in the transaction")
+	}
+
+	if err := t.delete(t.valueKey(seq)); err != nil {
+		return errors.Wrap(err, "error calling delete")
+	}
+
+	if err := t.deleteFromKeyIndex(t.keyIndexSequenceKey(seq)); err != nil {
+		return errors.Wrap(err, "error deleting from the key index")
+	}
+
+	return nil
+}
+
+func (t *TxBadgerDatabaseSystem) DeleteRange(start, end Sequence) error {
+	if start >= end {
+		return nil
+	}
+
+	keys := t.keys(badgerValuePrefix, t.valueKey(start), t.valueKey(end))
+
+	for _, key := range keys {
+		if err := t.reserve(badgerWrite{key: key}); err != nil {
+			return errors.Wrap(err, "error reserving space in the transaction")
+		}
+
+		if err := t.delete(key); err != nil {
+			return errors.Wrap(err, "error calling delete")
+		}
+	}
+
+	sequenceKeys := t.keys(badgerKeyIndexSequencePrefix, t.keyIndexSequenceKey(start), t.keyIndexSequenceKey(end))
+
+	for _, sequenceKey := range sequenceKeys {
+		if err := t.reserve(
+			badgerWrite{key: t.keyIndexKey(Key{})},
+			badgerWrite{key: sequenceKey},
+		); err != nil {
+			return errors.Wrap(err, "error reserving space in the transaction")
+		}
+
+		if err := t.deleteFromKeyIndex(sequenceKey); err != nil {
+			return errors.Wrap(err, "error deleting from the key index")
+		}
+	}
+
+	return nil
+}
+
+// deleteFromKeyIndex removes the key under which the value was appended
+// from the key index, if it was appended with a key.
+func (t *TxBadgerDatabaseSystem) deleteFromKeyIndex(sequenceKey []byte) error {
+	item, err := t.tx.Get(sequenceKey)
+	if err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, "error calling get")
+	}
+
+	var key Key
+	if err := item.Value(func(val []byte) error {
+		copy(key[:], val)
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "error calling value")
+	}
+
+	if err := t.delete(t.keyIndexKey(key)); err != nil {
+		return errors.Wrap(err, "error deleting the key")
+	}
+
+	if err := t.delete(sequenceKey); err != nil {
+		return errors.Wrap(err, "error deleting the sequence")
 	}
 
 	return nil
@@ -92,6 +371,9 @@ func (t *TxBadgerDatabaseSystem) Append(value []byte) error {
 func (t *TxBadgerDatabaseSystem) Get(seq Sequence) ([]byte, error) {
 	item, err := t.tx.Get(t.valueKey(seq))
 	if err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, errors.Wrap(err, "error calling get")
 	}
 
@@ -104,14 +386,21 @@ func (t *TxBadgerDatabaseSystem) Get(seq Sequence) ([]byte, error) {
 }
 
 func (t *TxBadgerDatabaseSystem) Iterate(start Sequence, limit int, fn func(item Item) error) error {
-	it := t.tx.NewIterator(badger.DefaultIteratorOptions)
+	if limit <= 0 {
+		return nil
+	}
+
+	options := badger.DefaultIteratorOptions
+	options.Prefix = badgerValuePrefix
+
+	it := t.tx.NewIterator(options)
 	defer it.Close()
 
 	counter := 0
 	for it.Seek(t.valueKey(start)); it.Valid(); it.Next() {
 		item := it.Item()
 		if err := item.Value(func(val []byte) error {
-			seq := unmarshalSequence(item.Key())
+			seq := unmarshalSequence(item.Key()[len(badgerValuePrefix):])
 			if err := fn(Item{seq, val}); err != nil {
 				return errors.Wrap(err, "function returned an error")
 			}
@@ -128,14 +417,14 @@ func (t *TxBadgerDatabaseSystem) Iterate(start Sequence, limit int, fn func(item
 	return nil
 }
 
-func (t *TxBadgerDatabaseSystem) getNextSequence() (Sequence, error) {
+func (t *TxBadgerDatabaseSystem) LastSequence() (Sequence, bool, error) {
 	item, err := t.tx.Get(badgerLastSequenceKey)
 	if err != nil {
 		if errors.Is(err, badger.ErrKeyNotFound) {
-			return 0, nil
+			return 0, false, nil
 		}
 
-		return 0, errors.Wrap(err, "error calling next sequence")
+		return 0, false, errors.Wrap(err, "error calling get")
 	}
 
 	var lastSequence Sequence
@@ -145,16 +434,384 @@ func (t *TxBadgerDatabaseSystem) getNextSequence() (Sequence, error) {
 		lastSequence = tmp
 		return nil
 	}); err != nil {
-		return 0, errors.Wrap(err, "error calling item value")
+		return 0, false, errors.Wrap(err, "error calling item value")
+	}
+
+	return lastSequence, true, nil
+}
+
+func (t *TxBadgerDatabaseSystem) IterateRange(start, end Sequence, direction Direction, fn func(item Item) error) error {
+	if start >= end {
+		return nil
+	}
+
+	options := badger.DefaultIteratorOptions
+	options.Prefix = badgerValuePrefix
+	options.Reverse = direction == Backward
+
+	it := t.tx.NewIterator(options)
+	defer it.Close()
+
+	// in reverse mode seek finds the largest key smaller than or equal to
+	// the given key
+	seekTo := start
+	if direction == Backward {
+		seekTo = end - 1
+	}
+
+	for it.Seek(t.valueKey(seekTo)); it.Valid(); it.Next() {
+		item := it.Item()
+
+		seq := unmarshalSequence(item.Key()[len(badgerValuePrefix):])
+		if seq < start || seq >= end {
+			break
+		}
+
+		if err := item.Value(func(val []byte) error {
+			if err := fn(Item{seq, val}); err != nil {
+				return errors.Wrap(err, "function returned an error")
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error getting value")
+		}
+	}
+	return nil
+}
+
+func (t *TxBadgerDatabaseSystem) getNextSequence() (Sequence, error) {
+	lastSequence, ok, err := t.LastSequence()
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling last sequence")
+	}
+
+	if !ok {
+		return 0, nil
 	}
 
 	return lastSequence + 1, nil
 }
 
 func (t *TxBadgerDatabaseSystem) setLastSequence(seq Sequence) error {
-	return t.tx.Set(badgerLastSequenceKey, marshalSequence(seq))
+	return t.set(badgerLastSequenceKey, marshalSequence(seq))
+}
+
+func (t *TxBadgerDatabaseSystem) set(key, value []byte) error {
+	if err := t.tx.Set(key, value); err != nil {
+		return err
+	}
+
+	t.track(badgerWrite{key, value})
+	return nil
+}
+
+func (t *TxBadgerDatabaseSystem) delete(key []byte) error {
+	if err := t.tx.Delete(key); err != nil {
+		return err
+	}
+
+	t.track(badgerWrite{key: key})
+	return nil
+}
+
+// badgerWrite is an entry set or deleted by an operation.
+type badgerWrite struct {
+	key   []byte
+	value []byte
+}
+
+// estimatedSize is never smaller than the size which badger attributes to
+// the entry.
+func (w badgerWrite) estimatedSize() int64 {
+	// badger counts a value pointer instead of the value if the value is
+	// stored in the value log
+	const valuePointerSize = 12
+
+	valueSize := int64(len(w.value))
+	if valueSize < valuePointerSize {
+		valueSize = valuePointerSize
+	}
+
+	// meta, user meta and the version
+	return int64(len(w.key)) + valueSize + 2 + 10
+}
+
+func (t *TxBadgerDatabaseSystem) track(w badgerWrite) {
+	t.count++
+	t.size += w.estimatedSize()
+}
+
+// reserve is called before an operation with all entries which the operation
+// will write. If they wouldn't fit in the transaction the transaction is
+// split so that an operation is never split between two transactions, for
+// example a value is never committed without the last sequence. An operation
+// which doesn't fit in an empty transaction fails with ErrTxnTooBig.
+func (t *TxBadgerDatabaseSystem) reserve(writes ...badgerWrite) error {
+	// nothing would be gained by committing an empty transaction
+	if t.db == nil || t.count <= 1 {
+		return nil
+	}
+
+	count := t.count + int64(len(writes))
+	size := t.size
+	for _, w := range writes {
+		size += w.estimatedSize()
+	}
+
+	if count < t.db.MaxBatchCount() && size < t.db.MaxBatchSize() {
+		return nil
+	}
+
+	if err := t.split(); err != nil {
+		return errors.Wrap(err, "error splitting the transaction")
+	}
+
+	return nil
+}
+
+// split commits the transaction and continues in a new one.
+func (t *TxBadgerDatabaseSystem) split() error {
+	if err := t.commit(); err != nil {
+		return err
+	}
+
+	t.partiallyCommitted = true
+	t.tx = t.db.NewTransaction(true)
+	t.count = 1
+	t.size = badgerTxnOverhead
+	return nil
+}
+
+// commit returns ErrConflict only if no part of the update was committed
+// yet. Otherwise retrying the update would repeat the operations which were
+// already committed.
+func (t *TxBadgerDatabaseSystem) commit() error {
+	if err := t.tx.Commit(); err != nil {
+		if errors.Is(err, badger.ErrConflict) {
+			if t.partiallyCommitted {
+				return errors.Wrap(err, "conflict after a part of the update was committed")
+			}
+			return ErrConflict
+		}
+		return errors.Wrap(err, "error committing the transaction")
+	}
+
+	return nil
+}
+
+// keys returns copies of all keys with the given prefix in the range
+// [start, end), end is ignored if it is nil. The keys are collected before
+// they are modified as the transaction can't be changed while iterating.
+func (t *TxBadgerDatabaseSystem) keys(prefix, start, end []byte) [][]byte {
+	options := badger.DefaultIteratorOptions
+	options.Prefix = prefix
+	options.PrefetchValues = false
+
+	it := t.tx.NewIterator(options)
+	defer it.Close()
+
+	var keys [][]byte
+	for it.Seek(start); it.Valid(); it.Next() {
+		key := it.Item().KeyCopy(nil)
+		if end != nil && bytes.Compare(key, end) >= 0 {
+			break
+		}
+		keys = append(keys, key)
+	}
+	return keys
 }
 
 func (t *TxBadgerDatabaseSystem) valueKey(seq Sequence) []byte {
-	return append(badgerValuePrefix, marshalSequence(seq)...)
+	key := make([]byte, 0, len(badgerValuePrefix)+8)
+	key = append(key, badgerValuePrefix...)
+	return append(key, marshalSequence(seq)...)
+}
+
+// AppendWithKey reserves space in the same way as Append.
+func (t *TxBadgerDatabaseSystem) AppendWithKey(key Key, value []byte) (Sequence, error) {
+	if err := t.reserve(
+		badgerWrite{t.valueKey(0), value},
+		badgerWrite{badgerLastSequenceKey, marshalSequence(0)},
+		badgerWrite{t.keyIndexKey(key), marshalSequence(0)},
+		badgerWrite{t.keyIndexSequenceKey(0), key[:]},
+	); err != nil {
+		return 0, errors.Wrap(err, "error reserving space in the transaction")
+	}
+
+	seq, err := t.getNextSequence()
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling get next sequence")
+	}
+
+	if _, err := t.append(seq, value); err != nil {
+		return 0, errors.Wrap(err, "error calling append")
+	}
+
+	if err := t.set(t.keyIndexKey(key), marshalSequence(seq)); err != nil {
+		return 0, errors.Wrap(err, "error calling set")
+	}
+
+	if err := t.set(t.keyIndexSequenceKey(seq), key[:]); err != nil {
+		return 0, errors.Wrap(err, "error calling set")
+	}
+
+	return seq, nil
+}
+
+func (t *TxBadgerDatabaseSystem) GetSequence(key Key) (Sequence, error) {
+	item, err := t.tx.Get(t.keyIndexKey(key))
+	if err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return 0, ErrNotFound
+		}
+		return 0, errors.Wrap(err, "error calling get")
+	}
+
+	var seq Sequence
+	if err := item.Value(func(val []byte) error {
+		seq = unmarshalSequence(val)
+		return nil
+	}); err != nil {
+		return 0, errors.Wrap(err, "error calling value")
+	}
+
+	return seq, nil
+}
+
+func (t *TxBadgerDatabaseSystem) keyIndexKey(key Key) []byte {
+	v := make([]byte, 0, len(badgerKeyIndexPrefix)+len(key))
+	v = append(v, badgerKeyIndexPrefix...)
+	return append(v, key[:]...)
+}
+
+func (t *TxBadgerDatabaseSystem) keyIndexSequenceKey(seq Sequence) []byte {
+	v := make([]byte, 0, len(badgerKeyIndexSequencePrefix)+8)
+	v = append(v, badgerKeyIndexSequencePrefix...)
+	return append(v, marshalSequence(seq)...)
+}
+
+// AppendToFeed reserves space in the same way as Append.
+func (t *TxBadgerDatabaseSystem) AppendToFeed(author Author, value []byte) (Sequence, error) {
+	if err := t.reserve(
+		badgerWrite{t.feedValueKey(author, 0), value},
+		badgerWrite{t.feedLastSequenceKey(author), marshalSequence(0)},
+	); err != nil {
+		return 0, errors.Wrap(err, "error reserving space in the transaction")
+	}
+
+	seq, err := t.getNextFeedSequence(author)
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling get next feed sequence")
+	}
+
+	if err := t.set(t.feedValueKey(author, seq), value); err != nil {
+		return 0, errors.Wrap(err, "error calling set")
+	}
+
+	if err := t.set(t.feedLastSequenceKey(author), marshalSequence(seq)); err != nil {
+		return 0, errors.Wrap(err, "error calling set")
+	}
+
+	return seq, nil
+}
+
+func (t *TxBadgerDatabaseSystem) DeleteFeed(author Author) error {
+	prefix := t.feedValuePrefix(author)
+
+	keys := t.keys(prefix, prefix, nil)
+
+	keys = append(keys, t.feedLastSequenceKey(author))
+
+	for _, key := range keys {
+		if err := t.reserve(badgerWrite{key: key}); err != nil {
+			return errors.Wrap(err, "error reserving space in the transaction")
+		}
+
+		if err := t.delete(key); err != nil {
+			return errors.Wrap(err, "error calling delete")
+		}
+	}
+
+	return nil
+}
+
+func (t *TxBadgerDatabaseSystem) IterateFeed(author Author, from Sequence, limit int, fn func(item Item) error) error {
+	prefix := t.feedValuePrefix(author)
+
+	options := badger.DefaultIteratorOptions
+	options.Prefix = prefix
+
+	it := t.tx.NewIterator(options)
+	defer it.Close()
+
+	counter := 0
+	for it.Seek(t.feedValueKey(author, from)); it.Valid(); it.Next() {
+		item := it.Item()
+		if err := item.Value(func(val []byte) error {
+			seq := unmarshalSequence(item.Key()[len(prefix):])
+			if err := fn(Item{seq, val}); err != nil {
+				return errors.Wrap(err, "function returned an error")
+			}
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "error getting value")
+		}
+
+		counter++
+		if counter >= limit {
+			break
+		}
+	}
+	return nil
+}
+
+func (t *TxBadgerDatabaseSystem) LastFeedSequence(author Author) (Sequence, bool, error) {
+	item, err := t.tx.Get(t.feedLastSequenceKey(author))
+	if err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return 0, false, nil
+		}
+
+		return 0, false, errors.Wrap(err, "error calling get")
+	}
+
+	var lastSequence Sequence
+
+	if err := item.Value(func(val []byte) error {
+		lastSequence = unmarshalSequence(val)
+		return nil
+	}); err != nil {
+		return 0, false, errors.Wrap(err, "error calling item value")
+	}
+
+	return lastSequence, true, nil
+}
+
+func (t *TxBadgerDatabaseSystem) getNextFeedSequence(author Author) (Sequence, error) {
+	lastSequence, ok, err := t.LastFeedSequence(author)
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling last feed sequence")
+	}
+
+	if !ok {
+		return 0, nil
+	}
+
+	return lastSequence + 1, nil
+}
+
+func (t *TxBadgerDatabaseSystem) feedValuePrefix(author Author) []byte {
+	key := make([]byte, 0, len(badgerFeedValuePrefix)+len(author))
+	key = append(key, badgerFeedValuePrefix...)
+	return append(key, author[:]...)
+}
+
+func (t *TxBadgerDatabaseSystem) feedValueKey(author Author, seq Sequence) []byte {
+	return append(t.feedValuePrefix(author), marshalSequence(seq)...)
+}
+
+func (t *TxBadgerDatabaseSystem) feedLastSequenceKey(author Author) []byte {
+	key := make([]byte, 0, len(badgerFeedLastSequencePrefix)+len(author))
+	key = append(key, badgerFeedLastSequencePrefix...)
+	return append(key, author[:]...)
 }
@@ -0,0 +1,52 @@
+package db_benchmark
+
+import (
+	"testing"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBadgerSplitsTransactionsBetweenAppends(t *testing.T) {
+	databaseSystem, err := NewBadgerDatabaseSystem(fixtures.Directory(t, ""), func(options *badger.Options) {
+		options.MemTableSize = 1 << 20
+		options.ValueThreshold = 1 << 10
+	}, DefaultTransactionSize, DurabilityNone)
+	require.NoError(t, err)
+	defer databaseSystem.Close()
+
+	value := make([]byte, 1000)
+
+	err = databaseSystem.update(func(updater *TxBadgerDatabaseSystem) error {
+		for !updater.partiallyCommitted {
+			if _, err := updater.Append(value); err != nil {
+				return err
+			}
+		}
+
+		// the last sequence read by the transaction after the split is
+		// modified by a concurrent update
+		return databaseSystem.Update(func(updater Updater) error {
+			_, err := updater.Append(value)
+			return err
+		})
+	})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrConflict, "the update can't be retried as a part of it was committed")
+
+	err = databaseSystem.Read(func(reader Reader) error {
+		lastSequence, err := getLastSequence(reader)
+		require.NoError(t, err)
+
+		for seq := Sequence(0); seq <= lastSequence; seq++ {
+			_, err := reader.Get(seq)
+			require.NoError(t, err)
+		}
+
+		_, err = reader.Get(lastSequence + 1)
+		require.ErrorIs(t, err, ErrNotFound, "values must be committed together with the last sequence")
+		return nil
+	})
+	require.NoError(t, err)
+}
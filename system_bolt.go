@@ -1,7 +1,9 @@
 package db_benchmark
 
 import (
+	"os"
 	"path"
+	"sync"
 
 	"github.com/boreq/errors"
 	"github.com/golang/snappy"
@@ -14,13 +16,28 @@ type BoltCodec interface {
 }
 
 type BoltDatabaseSystem struct {
-	db              *bbolt.DB
+	path            string
+	options         *bbolt.Options
 	codec           BoltCodec
 	transactionSize int
+	groupCommitter  *groupCommitter
+
+	// mutex guards db which is replaced by Compact.
+	mutex sync.RWMutex
+	db    *bbolt.DB
 }
 
-func NewBoltDatabaseSystem(dir string, fn func(options *bbolt.Options), codec BoltCodec, transactionSize int) (*BoltDatabaseSystem, error) {
+// NewBoltDatabaseSystem maps DurabilityNone onto NoSync and NoFreelistSync.
+// With DurabilityGroupCommit NoSync is set and the database is synced after
+// transactions are committed, concurrent transactions share a sync.
+func NewBoltDatabaseSystem(dir string, fn func(options *bbolt.Options), codec BoltCodec, transactionSize int, durability string) (*BoltDatabaseSystem, error) {
+	if err := checkDurability(durability); err != nil {
+		return nil, errors.Wrap(err, "invalid durability")
+	}
+
 	options := *bbolt.DefaultOptions
+	options.NoSync = durability != DurabilityFsync
+	options.NoFreelistSync = durability == DurabilityNone
 
 	if fn != nil {
 		fn(&options)
@@ -32,7 +49,16 @@ func NewBoltDatabaseSystem(dir string, fn func(options *bbolt.Options), codec Bo
 		return nil, errors.Wrap(err, "error opening the database")
 	}
 
-	return &BoltDatabaseSystem{db: db, codec: codec, transactionSize: transactionSize}, nil
+	s := &BoltDatabaseSystem{db: db, path: f, options: &options, codec: codec, transactionSize: transactionSize}
+	if durability == DurabilityGroupCommit {
+		// the database is replaced by Compact, the sync is called by update
+		// which holds the read lock
+		s.groupCommitter = newGroupCommitter(func() error {
+			return s.db.Sync()
+		})
+	}
+
+	return s, nil
 }
 
 func (b *BoltDatabaseSystem) PreferredTransactionSize() int {
@@ -40,7 +66,7 @@ func (b *BoltDatabaseSystem) PreferredTransactionSize() int {
 }
 
 func (b *BoltDatabaseSystem) Update(fn func(updater Updater) error) error {
-	return b.db.Update(func(tx *bbolt.Tx) error {
+	return b.update(func(tx *bbolt.Tx) error {
 		updater, err := NewTxBoltDatabaseSystem(tx, b.codec)
 		if err != nil {
 			return errors.Wrap(err, "error creating a tx database system")
@@ -51,7 +77,7 @@ func (b *BoltDatabaseSystem) Update(fn func(updater Updater) error) error {
 }
 
 func (b *BoltDatabaseSystem) Read(fn func(reader Reader) error) error {
-	return b.db.View(func(tx *bbolt.Tx) error {
+	return b.view(func(tx *bbolt.Tx) error {
 		updater, err := NewTxBoltDatabaseSystem(tx, b.codec)
 		if err != nil {
 			return errors.Wrap(err, "error creating a tx database system")
@@ -61,14 +87,141 @@ func (b *BoltDatabaseSystem) Read(fn func(reader Reader) error) error {
 	})
 }
 
+func (b *BoltDatabaseSystem) UpdateFeeds(fn func(updater FeedUpdater) error) error {
+	return b.update(func(tx *bbolt.Tx) error {
+		updater, err := NewTxBoltFeedDatabaseSystem(tx, b.codec)
+		if err != nil {
+			return errors.Wrap(err, "error creating a tx feed database system")
+		}
+
+		return fn(updater)
+	})
+}
+
+func (b *BoltDatabaseSystem) ReadFeeds(fn func(reader FeedReader) error) error {
+	return b.view(func(tx *bbolt.Tx) error {
+		reader, err := NewTxBoltFeedDatabaseSystem(tx, b.codec)
+		if err != nil {
+			return errors.Wrap(err, "error creating a tx feed database system")
+		}
+
+		return fn(reader)
+	})
+}
+
+func (b *BoltDatabaseSystem) UpdateWithKeys(fn func(updater KeyIndexUpdater) error) error {
+	return b.update(func(tx *bbolt.Tx) error {
+		updater, err := NewTxBoltKeyIndexDatabaseSystem(tx, b.codec)
+		if err != nil {
+			return errors.Wrap(err, "error creating a tx key index database system")
+		}
+
+		return fn(updater)
+	})
+}
+
+func (b *BoltDatabaseSystem) ReadWithKeys(fn func(reader KeyIndexReader) error) error {
+	return b.view(func(tx *bbolt.Tx) error {
+		reader, err := NewTxBoltKeyIndexDatabaseSystem(tx, b.codec)
+		if err != nil {
+			return errors.Wrap(err, "error creating a tx key index database system")
+		}
+
+		return fn(reader)
+	})
+}
+
+// Compact copies the database to a new file as bbolt never shrinks the
+// database file. The copy replaces the database file and is used from then
+// on, the database isn't reopened so if compacting fails the database
+// system can still be used.
+func (b *BoltDatabaseSystem) Compact() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	tmp := b.path + ".compact"
+
+	dst, err := bbolt.Open(tmp, 0600, b.options)
+	if err != nil {
+		return errors.Wrap(err, "error opening the destination database")
+	}
+
+	if err := bbolt.Compact(dst, b.db, 0); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return errors.Wrap(err, "error calling compact")
+	}
+
+	// the options may disable syncing
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return errors.Wrap(err, "error syncing the destination database")
+	}
+
+	// the open database keeps using the replaced file until it is closed
+	if err := os.Rename(tmp, b.path); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return errors.Wrap(err, "error replacing the database")
+	}
+
+	db := b.db
+	b.db = dst
+
+	if err := db.Close(); err != nil {
+		return errors.Wrap(err, "error closing the replaced database")
+	}
+
+	return nil
+}
+
 func (b *BoltDatabaseSystem) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
 	return b.db.Close()
 }
 
 func (b *BoltDatabaseSystem) Sync() error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
 	return b.db.Sync()
 }
 
+func (b *BoltDatabaseSystem) view(fn func(tx *bbolt.Tx) error) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.db.View(fn)
+}
+
+func (b *BoltDatabaseSystem) update(fn func(tx *bbolt.Tx) error) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if err := b.db.Update(fn); err != nil {
+		return err
+	}
+
+	return b.committed()
+}
+
+// committed waits for the committed transaction to be synced if group commit
+// is used, otherwise NoSync decides if it was synced.
+func (b *BoltDatabaseSystem) committed() error {
+	if b.groupCommitter == nil {
+		return nil
+	}
+
+	if err := b.groupCommitter.Sync(); err != nil {
+		return errors.Wrap(err, "error syncing the committed transaction")
+	}
+
+	return nil
+}
+
 var boltBucketName = []byte("values")
 
 type TxBoltDatabaseSystem struct {
@@ -95,38 +248,125 @@ func NewTxBoltDatabaseSystem(tx *bbolt.Tx, codec BoltCodec) (*TxBoltDatabaseSyst
 	return s, nil
 }
 
-func (t *TxBoltDatabaseSystem) Append(value []byte) error {
+func (t *TxBoltDatabaseSystem) Append(value []byte) (Sequence, error) {
 	seq, err := t.getNextSequence()
 	if err != nil {
-		return errors.Wrap(err, "error calling get next sequence")
+		return 0, errors.Wrap(err, "error calling get next sequence")
 	}
 
 	encodedValue, err := t.codec.Encode(value)
 	if err != nil {
-		return errors.Wrap(err, "error calling encode")
+		return 0, errors.Wrap(err, "error calling encode")
+	}
+
+	if err := t.bucket.Put(marshalSequence(seq), encodedValue); err != nil {
+		return 0, errors.Wrap(err, "error calling put")
+	}
+
+	return seq, nil
+}
+
+func (t *TxBoltDatabaseSystem) Delete(seq Sequence) error {
+	if err := t.bucket.Delete(marshalSequence(seq)); err != nil {
+		return errors.Wrap(err, "error calling delete")
+	}
+
+	if err := t.deleteFromKeyIndex(seq); err != nil {
+		return errors.Wrap(err, "error deleting from the key index")
+	}
+
+	return nil
+}
+
+func (t *TxBoltDatabaseSystem) DeleteRange(start, end Sequence) error {
+	if start >= end {
+		return nil
+	}
+
+	// deleting using the cursor while iterating can skip keys
+	var sequences []Sequence
+
+	c := t.bucket.Cursor()
+	for k, _ := c.Seek(marshalSequence(start)); k != nil && unmarshalSequence(k) < end; k, _ = c.Next() {
+		sequences = append(sequences, unmarshalSequence(k))
+	}
+
+	for _, seq := range sequences {
+		if err := t.bucket.Delete(marshalSequence(seq)); err != nil {
+			return errors.Wrap(err, "error calling delete")
+		}
+
+		if err := t.deleteFromKeyIndex(seq); err != nil {
+			return errors.Wrap(err, "error deleting from the key index")
+		}
+	}
+
+	return nil
+}
+
+// deleteFromKeyIndex removes the key under which the value was appended
+// from the key index, if it was appended with a key.
+func (t *TxBoltDatabaseSystem) deleteFromKeyIndex(seq Sequence) error {
+	tx := t.bucket.Tx()
+
+	sequences := tx.Bucket(boltKeyIndexSequencesBucketName)
+	if sequences == nil {
+		return nil
+	}
+
+	key := sequences.Get(marshalSequence(seq))
+	if key == nil {
+		return nil
+	}
+
+	if err := tx.Bucket(boltKeyIndexBucketName).Delete(append([]byte(nil), key...)); err != nil {
+		return errors.Wrap(err, "error deleting the key")
+	}
+
+	if err := sequences.Delete(marshalSequence(seq)); err != nil {
+		return errors.Wrap(err, "error deleting the sequence")
 	}
 
-	return t.bucket.Put(marshalSequence(seq), encodedValue)
+	return nil
 }
 
 func (t *TxBoltDatabaseSystem) Get(seq Sequence) ([]byte, error) {
+	// the bucket doesn't exist in read-only transactions if nothing was
+	// ever appended
+	if t.bucket == nil {
+		return nil, ErrNotFound
+	}
+
 	encodedValue := t.bucket.Get(marshalSequence(seq))
+	if encodedValue == nil {
+		return nil, ErrNotFound
+	}
 
 	value, err := t.codec.Decode(encodedValue)
 	if err != nil {
-		return nil, errors.Wrap(err, "error calling encode")
+		return nil, errors.Wrap(err, "error calling decode")
 	}
 
 	return value, nil
 }
 
 func (t *TxBoltDatabaseSystem) Iterate(start Sequence, limit int, fn func(item Item) error) error {
+	if t.bucket == nil || limit <= 0 {
+		return nil
+	}
+
 	c := t.bucket.Cursor()
 	counter := 0
 
 	for k, v := c.Seek(marshalSequence(start)); k != nil; k, v = c.Next() {
 		seq := unmarshalSequence(k)
-		if err := fn(Item{seq, v}); err != nil {
+
+		value, err := t.codec.Decode(v)
+		if err != nil {
+			return errors.Wrap(err, "error calling decode")
+		}
+
+		if err := fn(Item{seq, value}); err != nil {
 			return errors.Wrap(err, "function returned an error")
 		}
 
@@ -139,6 +379,66 @@ func (t *TxBoltDatabaseSystem) Iterate(start Sequence, limit int, fn func(item I
 	return nil
 }
 
+func (t *TxBoltDatabaseSystem) IterateRange(start, end Sequence, direction Direction, fn func(item Item) error) error {
+	if t.bucket == nil || start >= end {
+		return nil
+	}
+
+	c := t.bucket.Cursor()
+
+	var k, v []byte
+	var next func() ([]byte, []byte)
+
+	switch direction {
+	case Forward:
+		k, v = c.Seek(marshalSequence(start))
+		next = c.Next
+	case Backward:
+		// seek positions the cursor on the first key greater than or equal
+		// to the given key
+		k, v = c.Seek(marshalSequence(end - 1))
+		if k == nil {
+			k, v = c.Last()
+		} else if unmarshalSequence(k) > end-1 {
+			k, v = c.Prev()
+		}
+		next = c.Prev
+	default:
+		return errors.New("unknown direction")
+	}
+
+	for ; k != nil; k, v = next() {
+		seq := unmarshalSequence(k)
+		if seq < start || seq >= end {
+			break
+		}
+
+		value, err := t.codec.Decode(v)
+		if err != nil {
+			return errors.Wrap(err, "error calling decode")
+		}
+
+		if err := fn(Item{seq, value}); err != nil {
+			return errors.Wrap(err, "function returned an error")
+		}
+	}
+
+	return nil
+}
+
+func (t *TxBoltDatabaseSystem) LastSequence() (Sequence, bool, error) {
+	if t.bucket == nil {
+		return 0, false, nil
+	}
+
+	seqInt := t.bucket.Sequence()
+	if seqInt == 0 {
+		return 0, false, nil
+	}
+
+	return Sequence(seqInt - 1), true, nil
+}
+
 func (t *TxBoltDatabaseSystem) getNextSequence() (Sequence, error) {
 	seqInt, err := t.bucket.NextSequence()
 	if err != nil {
@@ -148,6 +448,195 @@ func (t *TxBoltDatabaseSystem) getNextSequence() (Sequence, error) {
 	return Sequence(seqInt - 1), nil
 }
 
+var boltFeedsBucketName = []byte("feeds")
+
+// TxBoltFeedDatabaseSystem stores each feed in a separate bucket nested in
+// the feeds bucket.
+type TxBoltFeedDatabaseSystem struct {
+	bucket *bbolt.Bucket
+	codec  BoltCodec
+}
+
+func NewTxBoltFeedDatabaseSystem(tx *bbolt.Tx, codec BoltCodec) (*TxBoltFeedDatabaseSystem, error) {
+	s := &TxBoltFeedDatabaseSystem{
+		codec: codec,
+	}
+
+	if tx.Writable() {
+		bucket, err := tx.CreateBucketIfNotExists(boltFeedsBucketName)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating the bucket")
+		}
+
+		s.bucket = bucket
+	} else {
+		s.bucket = tx.Bucket(boltFeedsBucketName)
+	}
+
+	return s, nil
+}
+
+func (t *TxBoltFeedDatabaseSystem) AppendToFeed(author Author, value []byte) (Sequence, error) {
+	bucket, err := t.bucket.CreateBucketIfNotExists(author[:])
+	if err != nil {
+		return 0, errors.Wrap(err, "error creating the feed bucket")
+	}
+
+	seqInt, err := bucket.NextSequence()
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling next sequence")
+	}
+
+	seq := Sequence(seqInt - 1)
+
+	encodedValue, err := t.codec.Encode(value)
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling encode")
+	}
+
+	if err := bucket.Put(marshalSequence(seq), encodedValue); err != nil {
+		return 0, errors.Wrap(err, "error calling put")
+	}
+
+	return seq, nil
+}
+
+func (t *TxBoltFeedDatabaseSystem) DeleteFeed(author Author) error {
+	if t.feedBucket(author) == nil {
+		return nil
+	}
+
+	if err := t.bucket.DeleteBucket(author[:]); err != nil {
+		return errors.Wrap(err, "error deleting the feed bucket")
+	}
+
+	return nil
+}
+
+func (t *TxBoltFeedDatabaseSystem) IterateFeed(author Author, from Sequence, limit int, fn func(item Item) error) error {
+	bucket := t.feedBucket(author)
+	if bucket == nil {
+		return nil
+	}
+
+	c := bucket.Cursor()
+	counter := 0
+
+	for k, v := c.Seek(marshalSequence(from)); k != nil; k, v = c.Next() {
+		seq := unmarshalSequence(k)
+
+		value, err := t.codec.Decode(v)
+		if err != nil {
+			return errors.Wrap(err, "error calling decode")
+		}
+
+		if err := fn(Item{seq, value}); err != nil {
+			return errors.Wrap(err, "function returned an error")
+		}
+
+		counter++
+		if counter >= limit {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (t *TxBoltFeedDatabaseSystem) LastFeedSequence(author Author) (Sequence, bool, error) {
+	bucket := t.feedBucket(author)
+	if bucket == nil {
+		return 0, false, nil
+	}
+
+	seqInt := bucket.Sequence()
+	if seqInt == 0 {
+		return 0, false, nil
+	}
+
+	return Sequence(seqInt - 1), true, nil
+}
+
+func (t *TxBoltFeedDatabaseSystem) feedBucket(author Author) *bbolt.Bucket {
+	if t.bucket == nil {
+		return nil
+	}
+	return t.bucket.Bucket(author[:])
+}
+
+var boltKeyIndexBucketName = []byte("key_index")
+var boltKeyIndexSequencesBucketName = []byte("key_index_sequences")
+
+// TxBoltKeyIndexDatabaseSystem stores the key index in a separate bucket
+// which is updated in the same transaction as the values. The keys are also
+// stored by sequence so that they can be removed when values are deleted.
+type TxBoltKeyIndexDatabaseSystem struct {
+	*TxBoltDatabaseSystem
+	keys      *bbolt.Bucket
+	sequences *bbolt.Bucket
+}
+
+func NewTxBoltKeyIndexDatabaseSystem(tx *bbolt.Tx, codec BoltCodec) (*TxBoltKeyIndexDatabaseSystem, error) {
+	system, err := NewTxBoltDatabaseSystem(tx, codec)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating a tx database system")
+	}
+
+	s := &TxBoltKeyIndexDatabaseSystem{
+		TxBoltDatabaseSystem: system,
+	}
+
+	if tx.Writable() {
+		bucket, err := tx.CreateBucketIfNotExists(boltKeyIndexBucketName)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating the bucket")
+		}
+
+		s.keys = bucket
+
+		sequences, err := tx.CreateBucketIfNotExists(boltKeyIndexSequencesBucketName)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating the sequences bucket")
+		}
+
+		s.sequences = sequences
+	} else {
+		s.keys = tx.Bucket(boltKeyIndexBucketName)
+	}
+
+	return s, nil
+}
+
+func (t *TxBoltKeyIndexDatabaseSystem) AppendWithKey(key Key, value []byte) (Sequence, error) {
+	seq, err := t.Append(value)
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling append")
+	}
+
+	if err := t.keys.Put(key[:], marshalSequence(seq)); err != nil {
+		return 0, errors.Wrap(err, "error calling put")
+	}
+
+	if err := t.sequences.Put(marshalSequence(seq), key[:]); err != nil {
+		return 0, errors.Wrap(err, "error calling put")
+	}
+
+	return seq, nil
+}
+
+func (t *TxBoltKeyIndexDatabaseSystem) GetSequence(key Key) (Sequence, error) {
+	if t.keys == nil {
+		return 0, ErrNotFound
+	}
+
+	v := t.keys.Get(key[:])
+	if v == nil {
+		return 0, ErrNotFound
+	}
+
+	return unmarshalSequence(v), nil
+}
+
 type NoopBoltCodec struct {
 }
 
@@ -0,0 +1,33 @@
+package db_benchmark
+
+import (
+	"testing"
+
+	"github.com/boreq/db_benchmark/fixtures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltGroupCommitCallsUpdateOnce(t *testing.T) {
+	system, err := NewTestedDatabaseSystem(DatabaseSystemConfig{
+		Type:            BoltDatabaseSystemType,
+		Codec:           CodecNone,
+		TransactionSize: DefaultTransactionSize,
+		Durability:      DurabilityGroupCommit,
+	})
+	require.NoError(t, err)
+
+	databaseSystem, err := system.DatabaseSystemConstructor(fixtures.Directory(t, ""))
+	require.NoError(t, err)
+	defer databaseSystem.Close()
+
+	for i := 0; i < 10; i++ {
+		calls := 0
+		err = databaseSystem.Update(func(updater Updater) error {
+			calls++
+			_, err := updater.Append([]byte("value"))
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, calls)
+	}
+}
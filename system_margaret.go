@@ -4,33 +4,72 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"path"
 
 	"github.com/boreq/errors"
 	"github.com/golang/snappy"
 	"go.cryptoscope.co/luigi"
 	"go.cryptoscope.co/margaret"
+	"go.cryptoscope.co/margaret/indexes"
+	"go.cryptoscope.co/margaret/multilog/roaring"
+	multifs "go.cryptoscope.co/margaret/multilog/roaring/fs"
 	"go.cryptoscope.co/margaret/offset2"
+	"go.etcd.io/bbolt"
 )
 
 type MargaretDatabaseSystem struct {
-	log *offset2.OffsetLog
-}
+	dir             string
+	transactionSize int
+	log             *offset2.OffsetLog
+	feeds           *MargaretFeedDatabaseSystem
+	keys            *bbolt.DB
+	durability      string
+	groupCommitter  *groupCommitter
+}
+
+// NewMargaretDatabaseSystem syncs all files after every update unless
+// DurabilityNone is used as offset2 never syncs the log. With
+// DurabilityGroupCommit concurrent updates share a sync. Margaret doesn't
+// have transactions so the transaction size is only the number of values
+// appended in a single update.
+func NewMargaretDatabaseSystem(dir string, codec margaret.Codec, transactionSize int, durability string) (*MargaretDatabaseSystem, error) {
+	if err := checkDurability(durability); err != nil {
+		return nil, errors.Wrap(err, "invalid durability")
+	}
 
-func NewMargaretDatabaseSystem(dir string, codec margaret.Codec) (*MargaretDatabaseSystem, error) {
 	log, err := offset2.Open(dir, codec)
 	if err != nil {
 		return nil, errors.Wrap(err, "error calling open")
 	}
 
-	return &MargaretDatabaseSystem{log: log}, nil
+	feeds, err := NewMargaretFeedDatabaseSystem(path.Join(dir, "feeds"), codec)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the feed database system")
+	}
+
+	keys, err := bbolt.Open(path.Join(dir, "keys.bolt"), 0600, &bbolt.Options{
+		NoSync:         durability == DurabilityNone,
+		NoFreelistSync: durability == DurabilityNone,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening the key index")
+	}
+
+	s := &MargaretDatabaseSystem{dir: dir, transactionSize: transactionSize, log: log, feeds: feeds, keys: keys, durability: durability}
+	s.groupCommitter = newGroupCommitter(s.Sync)
+	return s, nil
 }
 
 func (b *MargaretDatabaseSystem) PreferredTransactionSize() int {
-	return 1000
+	return b.transactionSize
 }
 
 func (b *MargaretDatabaseSystem) Update(fn func(updater Updater) error) error {
-	return fn(b)
+	if err := fn(b); err != nil {
+		return err
+	}
+
+	return b.committed()
 }
 
 func (b *MargaretDatabaseSystem) Read(fn func(reader Reader) error) error {
@@ -38,16 +77,46 @@ func (b *MargaretDatabaseSystem) Read(fn func(reader Reader) error) error {
 }
 
 func (b *MargaretDatabaseSystem) Iterate(start Sequence, limit int, fn func(item Item) error) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	// the limit isn't passed to the query as it would also count the
+	// nulled values which are skipped
 	query, err := b.log.Query(
 		margaret.Gte(int64(start)),
-		margaret.Limit(limit),
 		margaret.SeqWrap(true),
 	)
 	if err != nil {
 		return errors.Wrap(err, "error performing a query")
 	}
 
-	for {
+	return b.iterateQuery(query, limit, fn)
+}
+
+func (b *MargaretDatabaseSystem) IterateRange(start, end Sequence, direction Direction, fn func(item Item) error) error {
+	if start >= end {
+		return nil
+	}
+
+	query, err := b.log.Query(
+		margaret.Gte(int64(start)),
+		margaret.Lt(int64(end)),
+		margaret.Reverse(direction == Backward),
+		margaret.SeqWrap(true),
+	)
+	if err != nil {
+		return errors.Wrap(err, "error performing a query")
+	}
+
+	return b.iterateQuery(query, -1, fn)
+}
+
+// iterateQuery calls fn for at most limit values returned by the query
+// skipping the values which were nulled. Negative limit means no limit.
+func (b *MargaretDatabaseSystem) iterateQuery(query luigi.Source, limit int, fn func(item Item) error) error {
+	counter := 0
+	for limit < 0 || counter < limit {
 		obj, err := query.Next(context.Background())
 		if err != nil {
 			if luigi.IsEOS(err) {
@@ -61,34 +130,464 @@ func (b *MargaretDatabaseSystem) Iterate(start Sequence, limit int, fn func(item
 			return errors.New("got a wrong type")
 		}
 
-		if err := fn(Item{Sequence(seqWrapper.Seq()), seqWrapper.Value().([]byte)}); err != nil {
-			return errors.Wrap(err, "function returned an error")
+		switch v := seqWrapper.Value().(type) {
+		case []byte:
+			if err := fn(Item{Sequence(seqWrapper.Seq()), v}); err != nil {
+				return errors.Wrap(err, "function returned an error")
+			}
+			counter++
+		case error:
+			if !margaret.IsErrNulled(v) {
+				return errors.Wrap(v, "query returned an error")
+			}
+		default:
+			return errors.New("got a wrong type of the value")
 		}
 	}
+
+	return nil
 }
 
-func (m *MargaretDatabaseSystem) Append(value []byte) error {
-	_, err := m.log.Append(value)
-	return err
+func (m *MargaretDatabaseSystem) Append(value []byte) (Sequence, error) {
+	seq, err := m.log.Append(value)
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling append")
+	}
+
+	return Sequence(seq), nil
+}
+
+// Delete nulls the value in the log, the space is never reclaimed as
+// offset2 doesn't support compaction.
+func (m *MargaretDatabaseSystem) Delete(seq Sequence) error {
+	lastSeq := m.log.Seq()
+	if lastSeq == margaret.SeqEmpty || seq > Sequence(lastSeq) {
+		return nil
+	}
+
+	if err := m.log.Null(int64(seq)); err != nil {
+		return errors.Wrap(err, "error calling null")
+	}
+
+	if err := m.deleteFromKeyIndex(seq, seq+1); err != nil {
+		return errors.Wrap(err, "error deleting from the key index")
+	}
+
+	return nil
+}
+
+func (m *MargaretDatabaseSystem) DeleteRange(start, end Sequence) error {
+	if err := m.nullRange(start, end); err != nil {
+		return errors.Wrap(err, "error nulling the values")
+	}
+
+	if err := m.deleteFromKeyIndex(start, end); err != nil {
+		return errors.Wrap(err, "error deleting from the key index")
+	}
+
+	return nil
+}
+
+func (m *MargaretDatabaseSystem) nullRange(start, end Sequence) error {
+	lastSeq := m.log.Seq()
+	if lastSeq == margaret.SeqEmpty {
+		return nil
+	}
+
+	if end > Sequence(lastSeq)+1 {
+		end = Sequence(lastSeq) + 1
+	}
+
+	for seq := start; seq < end; seq++ {
+		if err := m.log.Null(int64(seq)); err != nil {
+			return errors.Wrapf(err, "error nulling sequence %d", seq)
+		}
+	}
+
+	return nil
+}
+
+func (m *MargaretDatabaseSystem) LastSequence() (Sequence, bool, error) {
+	seq := m.log.Seq()
+	if seq == margaret.SeqEmpty {
+		return 0, false, nil
+	}
+
+	return Sequence(seq), true, nil
 }
 
 func (m *MargaretDatabaseSystem) Get(seq Sequence) ([]byte, error) {
+	lastSeq := m.log.Seq()
+	if lastSeq == margaret.SeqEmpty || seq > Sequence(lastSeq) {
+		return nil, ErrNotFound
+	}
+
 	v, err := m.log.Get(int64(seq))
 	if err != nil {
+		if margaret.IsErrNulled(err) {
+			return nil, ErrNotFound
+		}
 		return nil, errors.Wrap(err, "error calling get")
 	}
 
 	return v.([]byte), nil
 }
 
+func (b *MargaretDatabaseSystem) UpdateFeeds(fn func(updater FeedUpdater) error) error {
+	if err := fn(b.feeds); err != nil {
+		return err
+	}
+
+	return b.committed()
+}
+
+func (b *MargaretDatabaseSystem) ReadFeeds(fn func(reader FeedReader) error) error {
+	return fn(b.feeds)
+}
+
+// UpdateWithKeys maintains the key index as a side index stored outside of
+// the log. The index is updated after the values were appended to the log
+// which means that it can fall behind the log if the process crashes.
+func (b *MargaretDatabaseSystem) UpdateWithKeys(fn func(updater KeyIndexUpdater) error) error {
+	if err := b.keys.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(margaretKeyIndexBucketName)
+		if err != nil {
+			return errors.Wrap(err, "error creating the bucket")
+		}
+
+		sequences, err := tx.CreateBucketIfNotExists(margaretKeyIndexSequencesBucketName)
+		if err != nil {
+			return errors.Wrap(err, "error creating the sequences bucket")
+		}
+
+		return fn(&MargaretKeyIndexDatabaseSystem{MargaretDatabaseSystem: b, bucket: bucket, sequences: sequences})
+	}); err != nil {
+		return err
+	}
+
+	return b.committed()
+}
+
+func (b *MargaretDatabaseSystem) ReadWithKeys(fn func(reader KeyIndexReader) error) error {
+	return b.keys.View(func(tx *bbolt.Tx) error {
+		return fn(&MargaretKeyIndexDatabaseSystem{MargaretDatabaseSystem: b, bucket: tx.Bucket(margaretKeyIndexBucketName)})
+	})
+}
+
 func (b *MargaretDatabaseSystem) Close() error {
+	if err := b.keys.Close(); err != nil {
+		return errors.Wrap(err, "error closing the key index")
+	}
+
+	if err := b.feeds.Close(); err != nil {
+		return errors.Wrap(err, "error closing the feed database system")
+	}
+
 	return b.log.Close()
 }
 
 func (b *MargaretDatabaseSystem) Sync() error {
+	if err := syncFiles(b.dir); err != nil {
+		return errors.Wrap(err, "error syncing the log")
+	}
+
+	if err := b.keys.Sync(); err != nil {
+		return errors.Wrap(err, "error syncing the key index")
+	}
+
+	return b.feeds.Sync()
+}
+
+// committed syncs all files after an update according to the durability.
+func (b *MargaretDatabaseSystem) committed() error {
+	switch b.durability {
+	case DurabilityFsync:
+		return b.Sync()
+	case DurabilityGroupCommit:
+		return b.groupCommitter.Sync()
+	default:
+		return nil
+	}
+}
+
+// deleteFromKeyIndex removes the keys of the values in the range [start,
+// end) from the key index. The key index is stored separately so it is
+// updated after the values were nulled. A write transaction is started only
+// if some of the values were appended with a key.
+func (m *MargaretDatabaseSystem) deleteFromKeyIndex(start, end Sequence) error {
+	var indexed bool
+
+	if err := m.keys.View(func(tx *bbolt.Tx) error {
+		sequences := tx.Bucket(margaretKeyIndexSequencesBucketName)
+		if sequences == nil {
+			return nil
+		}
+
+		k, _ := sequences.Cursor().Seek(marshalSequence(start))
+		indexed = k != nil && unmarshalSequence(k) < end
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "error checking the key index")
+	}
+
+	if !indexed {
+		return nil
+	}
+
+	return m.keys.Update(func(tx *bbolt.Tx) error {
+		return deleteFromMargaretKeyIndex(tx, start, end)
+	})
+}
+
+func deleteFromMargaretKeyIndex(tx *bbolt.Tx, start, end Sequence) error {
+	sequences := tx.Bucket(margaretKeyIndexSequencesBucketName)
+	if sequences == nil {
+		return nil
+	}
+
+	keys := tx.Bucket(margaretKeyIndexBucketName)
+
+	// deleting using the cursor while iterating can skip keys
+	var toDelete [][]byte
+
+	c := sequences.Cursor()
+	for k, v := c.Seek(marshalSequence(start)); k != nil && unmarshalSequence(k) < end; k, v = c.Next() {
+		if err := keys.Delete(append([]byte(nil), v...)); err != nil {
+			return errors.Wrap(err, "error deleting the key")
+		}
+		toDelete = append(toDelete, append([]byte(nil), k...))
+	}
+
+	for _, k := range toDelete {
+		if err := sequences.Delete(k); err != nil {
+			return errors.Wrap(err, "error deleting the sequence")
+		}
+	}
+
+	return nil
+}
+
+var margaretKeyIndexBucketName = []byte("key_index")
+var margaretKeyIndexSequencesBucketName = []byte("key_index_sequences")
+
+// MargaretKeyIndexDatabaseSystem stores the keys by sequence as well so
+// that they can be removed when values are deleted.
+type MargaretKeyIndexDatabaseSystem struct {
+	*MargaretDatabaseSystem
+	bucket    *bbolt.Bucket
+	sequences *bbolt.Bucket
+}
+
+func (m *MargaretKeyIndexDatabaseSystem) AppendWithKey(key Key, value []byte) (Sequence, error) {
+	seq, err := m.Append(value)
+	if err != nil {
+		return 0, errors.Wrap(err, "error calling append")
+	}
+
+	if err := m.bucket.Put(key[:], marshalSequence(seq)); err != nil {
+		return 0, errors.Wrap(err, "error calling put")
+	}
+
+	if err := m.sequences.Put(marshalSequence(seq), key[:]); err != nil {
+		return 0, errors.Wrap(err, "error calling put")
+	}
+
+	return seq, nil
+}
+
+// Delete uses the transaction of the key index as the key index can't be
+// updated in a separate transaction while this one is open.
+func (m *MargaretKeyIndexDatabaseSystem) Delete(seq Sequence) error {
+	return m.DeleteRange(seq, seq+1)
+}
+
+func (m *MargaretKeyIndexDatabaseSystem) DeleteRange(start, end Sequence) error {
+	if err := m.nullRange(start, end); err != nil {
+		return errors.Wrap(err, "error nulling the values")
+	}
+
+	if err := deleteFromMargaretKeyIndex(m.bucket.Tx(), start, end); err != nil {
+		return errors.Wrap(err, "error deleting from the key index")
+	}
+
+	return nil
+}
+
+func (m *MargaretKeyIndexDatabaseSystem) GetSequence(key Key) (Sequence, error) {
+	if m.bucket == nil {
+		return 0, ErrNotFound
+	}
+
+	v := m.bucket.Get(key[:])
+	if v == nil {
+		return 0, ErrNotFound
+	}
+
+	return unmarshalSequence(v), nil
+}
+
+// MargaretFeedDatabaseSystem stores values of all feeds in a single log and
+// keeps track of which values belong to which feed using a multilog.
+type MargaretFeedDatabaseSystem struct {
+	dir      string
+	log      *offset2.OffsetLog
+	multilog *roaring.MultiLog
+}
+
+func NewMargaretFeedDatabaseSystem(dir string, codec margaret.Codec) (*MargaretFeedDatabaseSystem, error) {
+	log, err := offset2.Open(path.Join(dir, "log"), codec)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening the log")
+	}
+
+	multilog, err := multifs.NewMultiLog(path.Join(dir, "multilog"))
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening the multilog")
+	}
+
+	return &MargaretFeedDatabaseSystem{dir: dir, log: log, multilog: multilog}, nil
+}
+
+func (m *MargaretFeedDatabaseSystem) AppendToFeed(author Author, value []byte) (Sequence, error) {
+	sublog, err := m.multilog.Get(m.addr(author))
+	if err != nil {
+		return 0, errors.Wrap(err, "error getting the sublog")
+	}
+
+	logSeq, err := m.log.Append(value)
+	if err != nil {
+		return 0, errors.Wrap(err, "error appending to the log")
+	}
+
+	if _, err := sublog.Append(logSeq); err != nil {
+		return 0, errors.Wrap(err, "error appending to the sublog")
+	}
+
+	return Sequence(sublog.Seq()), nil
+}
+
+// DeleteFeed nulls the values of the feed in the log and removes its
+// sublog from the multilog.
+func (m *MargaretFeedDatabaseSystem) DeleteFeed(author Author) error {
+	sublog, err := m.multilog.Get(m.addr(author))
+	if err != nil {
+		return errors.Wrap(err, "error getting the sublog")
+	}
+
+	query, err := sublog.Query()
+	if err != nil {
+		return errors.Wrap(err, "error performing a query")
+	}
+
+	for {
+		obj, err := query.Next(context.Background())
+		if err != nil {
+			if luigi.IsEOS(err) {
+				break
+			}
+			return errors.Wrap(err, "error getting the next value")
+		}
+
+		logSeq, ok := obj.(int64)
+		if !ok {
+			return errors.New("got a wrong type of the log sequence")
+		}
+
+		if err := m.log.Null(logSeq); err != nil {
+			return errors.Wrap(err, "error calling null")
+		}
+	}
+
+	if err := m.multilog.Delete(m.addr(author)); err != nil {
+		return errors.Wrap(err, "error deleting the sublog")
+	}
+
 	return nil
 }
 
+func (m *MargaretFeedDatabaseSystem) IterateFeed(author Author, from Sequence, limit int, fn func(item Item) error) error {
+	sublog, err := m.multilog.Get(m.addr(author))
+	if err != nil {
+		return errors.Wrap(err, "error getting the sublog")
+	}
+
+	query, err := sublog.Query(
+		margaret.Gte(int64(from)),
+		margaret.Limit(limit),
+		margaret.SeqWrap(true),
+	)
+	if err != nil {
+		return errors.Wrap(err, "error performing a query")
+	}
+
+	for {
+		obj, err := query.Next(context.Background())
+		if err != nil {
+			if luigi.IsEOS(err) {
+				return nil
+			}
+			return errors.Wrap(err, "error getting the next value")
+		}
+
+		seqWrapper, ok := obj.(margaret.SeqWrapper)
+		if !ok {
+			return errors.New("got a wrong type")
+		}
+
+		logSeq, ok := seqWrapper.Value().(int64)
+		if !ok {
+			return errors.New("got a wrong type of the log sequence")
+		}
+
+		value, err := m.log.Get(logSeq)
+		if err != nil {
+			return errors.Wrap(err, "error getting the value from the log")
+		}
+
+		if err := fn(Item{Sequence(seqWrapper.Seq()), value.([]byte)}); err != nil {
+			return errors.Wrap(err, "function returned an error")
+		}
+	}
+}
+
+func (m *MargaretFeedDatabaseSystem) LastFeedSequence(author Author) (Sequence, bool, error) {
+	sublog, err := m.multilog.Get(m.addr(author))
+	if err != nil {
+		return 0, false, errors.Wrap(err, "error getting the sublog")
+	}
+
+	seq := sublog.Seq()
+	if seq == margaret.SeqEmpty {
+		return 0, false, nil
+	}
+
+	return Sequence(seq), true, nil
+}
+
+func (m *MargaretFeedDatabaseSystem) Sync() error {
+	if err := syncFiles(path.Join(m.dir, "log")); err != nil {
+		return errors.Wrap(err, "error syncing the log")
+	}
+
+	if err := m.multilog.Flush(); err != nil {
+		return errors.Wrap(err, "error flushing the multilog")
+	}
+
+	return syncFiles(path.Join(m.dir, "multilog"))
+}
+
+func (m *MargaretFeedDatabaseSystem) Close() error {
+	if err := m.multilog.Close(); err != nil {
+		return errors.Wrap(err, "error closing the multilog")
+	}
+
+	return m.log.Close()
+}
+
+func (m *MargaretFeedDatabaseSystem) addr(author Author) indexes.Addr {
+	return indexes.Addr(author[:])
+}
+
 type MargaretCodec struct {
 }
 
@@ -0,0 +1,262 @@
+package db_benchmark
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/boreq/errors"
+	"github.com/dgraph-io/badger/v4"
+	badgeroptions "github.com/dgraph-io/badger/v4/options"
+	"go.cryptoscope.co/margaret"
+	"go.etcd.io/bbolt"
+)
+
+const DefaultTransactionSize = 5000
+
+const (
+	BoltDatabaseSystemType     = "bbolt"
+	BadgerDatabaseSystemType   = "badger"
+	MargaretDatabaseSystemType = "margaret"
+)
+
+const (
+	CodecNone   = "none"
+	CodecSnappy = "snappy"
+	CodecZSTD   = "zstd"
+)
+
+// DatabaseSystemConfig describes a single database system which should be
+// benchmarked.
+type DatabaseSystemConfig struct {
+	// Type is one of BoltDatabaseSystemType, BadgerDatabaseSystemType or
+	// MargaretDatabaseSystemType.
+	Type string
+
+	// Name is used as a prefix of the name of the database system. Defaults
+	// to Type.
+	Name string
+
+	// Codec is one of CodecNone, CodecSnappy or CodecZSTD.
+	Codec string
+
+	// TransactionSize is the number of values appended in a single
+	// update. Badger splits transactions which grow too big.
+	TransactionSize int
+
+	// Durability is one of DurabilityNone, DurabilityGroupCommit or
+	// DurabilityFsync.
+	Durability string
+
+	// Options are specific to each type of a database system, see
+	// applyBoltOptions and applyBadgerOptions.
+	Options map[string]string
+}
+
+func NewTestedDatabaseSystem(config DatabaseSystemConfig) (TestedDatabaseSystem, error) {
+	name := config.Name
+	if name == "" {
+		name = config.Type
+	}
+
+	if config.Codec != CodecNone {
+		name += "_" + config.Codec
+	}
+
+	if err := checkDurability(config.Durability); err != nil {
+		return TestedDatabaseSystem{}, errors.Wrap(err, "invalid durability")
+	}
+
+	if config.TransactionSize <= 0 {
+		return TestedDatabaseSystem{}, errors.New("transaction size must be positive")
+	}
+
+	durability := config.Durability
+	name += "_" + durability
+
+	switch config.Type {
+	case BoltDatabaseSystemType:
+		codec, err := newBoltCodec(config.Codec)
+		if err != nil {
+			return TestedDatabaseSystem{}, errors.Wrap(err, "error creating the codec")
+		}
+
+		optionsFn, err := applyBoltOptions(config.Options)
+		if err != nil {
+			return TestedDatabaseSystem{}, errors.Wrap(err, "error applying options")
+		}
+
+		transactionSize := config.TransactionSize
+
+		return TestedDatabaseSystem{
+			Name:   name + "_" + strconv.Itoa(transactionSize),
+			Config: config,
+			DatabaseSystemConstructor: func(dir string) (DatabaseSystem, error) {
+				return NewBoltDatabaseSystem(dir, optionsFn, codec, transactionSize, durability)
+			},
+		}, nil
+	case BadgerDatabaseSystemType:
+		compression, err := newBadgerCompression(config.Codec)
+		if err != nil {
+			return TestedDatabaseSystem{}, errors.Wrap(err, "error selecting compression")
+		}
+
+		optionsFn, err := applyBadgerOptions(config.Options)
+		if err != nil {
+			return TestedDatabaseSystem{}, errors.Wrap(err, "error applying options")
+		}
+
+		transactionSize := config.TransactionSize
+
+		return TestedDatabaseSystem{
+			Name:   name + "_" + strconv.Itoa(transactionSize),
+			Config: config,
+			DatabaseSystemConstructor: func(dir string) (DatabaseSystem, error) {
+				return NewBadgerDatabaseSystem(dir, func(options *badger.Options) {
+					options.Compression = compression
+					optionsFn(options)
+				}, transactionSize, durability)
+			},
+		}, nil
+	case MargaretDatabaseSystemType:
+		if len(config.Options) != 0 {
+			return TestedDatabaseSystem{}, errors.New("margaret doesn't support any options")
+		}
+
+		codec, err := newMargaretCodec(config.Codec)
+		if err != nil {
+			return TestedDatabaseSystem{}, errors.Wrap(err, "error creating the codec")
+		}
+
+		transactionSize := config.TransactionSize
+
+		return TestedDatabaseSystem{
+			Name:   name + "_" + strconv.Itoa(transactionSize),
+			Config: config,
+			DatabaseSystemConstructor: func(dir string) (DatabaseSystem, error) {
+				return NewMargaretDatabaseSystem(dir, codec, transactionSize, durability)
+			},
+		}, nil
+	default:
+		return TestedDatabaseSystem{}, fmt.Errorf("unknown database system type '%s'", config.Type)
+	}
+}
+
+func newBoltCodec(codec string) (BoltCodec, error) {
+	switch codec {
+	case CodecNone:
+		return NewNoopBoltCodec(), nil
+	case CodecSnappy:
+		return NewSnappyBoltCodec(), nil
+	case CodecZSTD:
+		return NewZSTDBoltCodec(), nil
+	default:
+		return nil, fmt.Errorf("unknown codec '%s'", codec)
+	}
+}
+
+func newBadgerCompression(codec string) (badgeroptions.CompressionType, error) {
+	switch codec {
+	case CodecNone:
+		return badgeroptions.None, nil
+	case CodecSnappy:
+		return badgeroptions.Snappy, nil
+	case CodecZSTD:
+		return badgeroptions.ZSTD, nil
+	default:
+		return 0, fmt.Errorf("unknown codec '%s'", codec)
+	}
+}
+
+func newMargaretCodec(codec string) (margaret.Codec, error) {
+	switch codec {
+	case CodecNone:
+		return NewMargaretCodec(), nil
+	case CodecSnappy:
+		return NewMargaretSnappyCodec(), nil
+	case CodecZSTD:
+		return NewMargaretZSTDCodec(), nil
+	default:
+		return nil, fmt.Errorf("unknown codec '%s'", codec)
+	}
+}
+
+// applyBoltOptions supports the following options: no_freelist_sync,
+// initial_mmap_size. NoSync is controlled by the durability.
+func applyBoltOptions(options map[string]string) (func(*bbolt.Options), error) {
+	var fns []func(*bbolt.Options)
+
+	for key, value := range options {
+		switch key {
+		case "no_freelist_sync":
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing '%s'", key)
+			}
+			fns = append(fns, func(o *bbolt.Options) { o.NoFreelistSync = v })
+		case "initial_mmap_size":
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing '%s'", key)
+			}
+			fns = append(fns, func(o *bbolt.Options) { o.InitialMmapSize = v })
+		default:
+			return nil, fmt.Errorf("unknown bbolt option '%s'", key)
+		}
+	}
+
+	return func(o *bbolt.Options) {
+		for _, fn := range fns {
+			fn(o)
+		}
+	}, nil
+}
+
+// applyBadgerOptions supports the following options: block_cache_size,
+// index_cache_size, value_log_file_size, value_threshold, num_memtables.
+// SyncWrites is controlled by the durability.
+func applyBadgerOptions(options map[string]string) (func(*badger.Options), error) {
+	var fns []func(*badger.Options)
+
+	for key, value := range options {
+		switch key {
+		case "block_cache_size":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing '%s'", key)
+			}
+			fns = append(fns, func(o *badger.Options) { o.BlockCacheSize = v })
+		case "index_cache_size":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing '%s'", key)
+			}
+			fns = append(fns, func(o *badger.Options) { o.IndexCacheSize = v })
+		case "value_log_file_size":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing '%s'", key)
+			}
+			fns = append(fns, func(o *badger.Options) { o.ValueLogFileSize = v })
+		case "value_threshold":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing '%s'", key)
+			}
+			fns = append(fns, func(o *badger.Options) { o.ValueThreshold = v })
+		case "num_memtables":
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing '%s'", key)
+			}
+			fns = append(fns, func(o *badger.Options) { o.NumMemtables = v })
+		default:
+			return nil, fmt.Errorf("unknown badger option '%s'", key)
+		}
+	}
+
+	return func(o *badger.Options) {
+		for _, fn := range fns {
+			fn(o)
+		}
+	}, nil
+}
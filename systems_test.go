@@ -0,0 +1,20 @@
+package db_benchmark_test
+
+import (
+	"testing"
+
+	dbbenchmark "github.com/boreq/db_benchmark"
+	"github.com/boreq/db_benchmark/dbtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseSystems(t *testing.T) {
+	systems, err := dbbenchmark.DefaultMatrix().DatabaseSystems()
+	require.NoError(t, err)
+
+	for _, system := range systems {
+		t.Run(system.Name, func(t *testing.T) {
+			dbtest.TestDatabaseSystem(t, system.DatabaseSystemConstructor)
+		})
+	}
+}
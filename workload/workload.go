@@ -0,0 +1,192 @@
+// Package workload defines the core workloads of the Yahoo! Cloud Serving
+// Benchmark in terms of an append-only log in which sequences are used as
+// keys. Workloads only describe the mix of operations and the distribution of
+// accessed keys, they are executed against database systems by the
+// benchmarks.
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/boreq/errors"
+)
+
+// Operation is a type of operation performed by a workload.
+type Operation string
+
+const (
+	// Read gets a single value.
+	Read Operation = "read"
+
+	// Update appends a new version of an existing value as values in the
+	// log can't be modified.
+	Update Operation = "update"
+
+	// Insert appends a new value.
+	Insert Operation = "insert"
+
+	// Scan iterates over a number of consecutive values.
+	Scan Operation = "scan"
+
+	// ReadModifyWrite gets a single value and then appends its new version.
+	ReadModifyWrite Operation = "read_modify_write"
+)
+
+// Operations lists all operations in the order in which they are reported.
+var Operations = []Operation{Read, Update, Insert, Scan, ReadModifyWrite}
+
+// Distributions of keys accessed by the read, update, scan and
+// read-modify-write operations.
+const (
+	DistributionUniform = "uniform"
+	DistributionZipfian = "zipfian"
+	DistributionLatest  = "latest"
+)
+
+// DefaultMaxScanLength is the maximum number of values visited by a scan
+// used by the core workloads.
+const DefaultMaxScanLength = 100
+
+// Workload describes a mix of operations. The percentages of all operations
+// must add up to 100.
+type Workload struct {
+	// Name is a single letter for the core workloads.
+	Name string
+
+	ReadPercentage            int
+	UpdatePercentage          int
+	InsertPercentage          int
+	ScanPercentage            int
+	ReadModifyWritePercentage int
+
+	// RequestDistribution is one of DistributionUniform,
+	// DistributionZipfian or DistributionLatest.
+	RequestDistribution string
+
+	// MaxScanLength is the maximum number of values visited by a scan, the
+	// length of each scan is chosen uniformly from [1, MaxScanLength].
+	MaxScanLength int
+}
+
+// CoreWorkloads returns YCSB core workloads A–F.
+func CoreWorkloads() []Workload {
+	return []Workload{
+		{
+			// update heavy, e.g. a session store recording recent actions
+			Name:                "a",
+			ReadPercentage:      50,
+			UpdatePercentage:    50,
+			RequestDistribution: DistributionZipfian,
+		},
+		{
+			// read mostly, e.g. photo tagging
+			Name:                "b",
+			ReadPercentage:      95,
+			UpdatePercentage:    5,
+			RequestDistribution: DistributionZipfian,
+		},
+		{
+			// read only, e.g. a user profile cache
+			Name:                "c",
+			ReadPercentage:      100,
+			RequestDistribution: DistributionZipfian,
+		},
+		{
+			// read latest, e.g. user status updates
+			Name:                "d",
+			ReadPercentage:      95,
+			InsertPercentage:    5,
+			RequestDistribution: DistributionLatest,
+		},
+		{
+			// short ranges, e.g. threaded conversations
+			Name:                "e",
+			ScanPercentage:      95,
+			InsertPercentage:    5,
+			RequestDistribution: DistributionZipfian,
+			MaxScanLength:       DefaultMaxScanLength,
+		},
+		{
+			// read-modify-write, e.g. a user database
+			Name:                      "f",
+			ReadPercentage:            50,
+			ReadModifyWritePercentage: 50,
+			RequestDistribution:       DistributionZipfian,
+		},
+	}
+}
+
+// CoreWorkload returns the core workload with the given name.
+func CoreWorkload(name string) (Workload, error) {
+	for _, w := range CoreWorkloads() {
+		if w.Name == name {
+			return w, nil
+		}
+	}
+	return Workload{}, fmt.Errorf("unknown core workload '%s'", name)
+}
+
+// Validate checks if the workload is correctly configured.
+func (w Workload) Validate() error {
+	sum := 0
+	for _, operation := range Operations {
+		percentage := w.Percentage(operation)
+		if percentage < 0 {
+			return fmt.Errorf("percentage of '%s' can't be negative", operation)
+		}
+		sum += percentage
+	}
+
+	if sum != 100 {
+		return errors.New("percentages of operations must add up to 100")
+	}
+
+	switch w.RequestDistribution {
+	case DistributionUniform, DistributionZipfian, DistributionLatest:
+	default:
+		return fmt.Errorf("unknown request distribution '%s'", w.RequestDistribution)
+	}
+
+	if w.ScanPercentage > 0 && w.MaxScanLength <= 0 {
+		return errors.New("max scan length must be positive")
+	}
+
+	return nil
+}
+
+// Percentage returns the percentage of the given operation.
+func (w Workload) Percentage(operation Operation) int {
+	switch operation {
+	case Read:
+		return w.ReadPercentage
+	case Update:
+		return w.UpdatePercentage
+	case Insert:
+		return w.InsertPercentage
+	case Scan:
+		return w.ScanPercentage
+	case ReadModifyWrite:
+		return w.ReadModifyWritePercentage
+	default:
+		return 0
+	}
+}
+
+// NextOperation chooses the next operation according to the percentages.
+// The workload must be valid.
+func (w Workload) NextOperation(rnd *rand.Rand) Operation {
+	v := rnd.Intn(100)
+	for _, operation := range Operations {
+		v -= w.Percentage(operation)
+		if v < 0 {
+			return operation
+		}
+	}
+	return Operations[len(Operations)-1]
+}
+
+// ScanLength chooses the number of values visited by a scan.
+func (w Workload) ScanLength(rnd *rand.Rand) int {
+	return 1 + rnd.Intn(w.MaxScanLength)
+}
@@ -0,0 +1,112 @@
+package workload
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoreWorkloadsAreValid(t *testing.T) {
+	workloads := CoreWorkloads()
+	require.Len(t, workloads, 6)
+
+	for _, w := range workloads {
+		t.Run(w.Name, func(t *testing.T) {
+			require.NoError(t, w.Validate())
+
+			v, err := CoreWorkload(w.Name)
+			require.NoError(t, err)
+			require.Equal(t, w, v)
+		})
+	}
+}
+
+func TestCoreWorkloadUnknown(t *testing.T) {
+	_, err := CoreWorkload("g")
+	require.EqualError(t, err, "unknown core workload 'g'")
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Workload      Workload
+		ExpectedError string
+	}{
+		{
+			Name: "percentages_must_add_up_to_100",
+			Workload: Workload{
+				ReadPercentage:      50,
+				UpdatePercentage:    40,
+				RequestDistribution: DistributionUniform,
+			},
+			ExpectedError: "percentages of operations must add up to 100",
+		},
+		{
+			Name: "negative_percentage",
+			Workload: Workload{
+				ReadPercentage:      110,
+				InsertPercentage:    -10,
+				RequestDistribution: DistributionUniform,
+			},
+			ExpectedError: "percentage of 'insert' can't be negative",
+		},
+		{
+			Name: "unknown_distribution",
+			Workload: Workload{
+				ReadPercentage:      100,
+				RequestDistribution: "hotspot",
+			},
+			ExpectedError: "unknown request distribution 'hotspot'",
+		},
+		{
+			Name: "scans_require_max_scan_length",
+			Workload: Workload{
+				ScanPercentage:      100,
+				RequestDistribution: DistributionUniform,
+			},
+			ExpectedError: "max scan length must be positive",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			require.EqualError(t, testCase.Workload.Validate(), testCase.ExpectedError)
+		})
+	}
+}
+
+func TestNextOperationFollowsPercentages(t *testing.T) {
+	const n = 100000
+
+	for _, w := range CoreWorkloads() {
+		t.Run(w.Name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(1))
+
+			counts := make(map[Operation]int)
+			for i := 0; i < n; i++ {
+				counts[w.NextOperation(rnd)]++
+			}
+
+			for _, operation := range Operations {
+				expected := float64(w.Percentage(operation)) / 100
+				actual := float64(counts[operation]) / n
+				require.True(t, math.Abs(expected-actual) < 0.01, "operation %s: expected %f, got %f", operation, expected, actual)
+			}
+		})
+	}
+}
+
+func TestScanLength(t *testing.T) {
+	w := Workload{MaxScanLength: 10}
+	rnd := rand.New(rand.NewSource(1))
+
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		length := w.ScanLength(rnd)
+		require.True(t, length >= 1 && length <= 10)
+		seen[length] = true
+	}
+	require.Len(t, seen, 10)
+}